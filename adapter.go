@@ -1,10 +1,65 @@
 package circuitbreaker
 
-import "time"
+import (
+	"context"
+	"time"
+
+	goCache "github.com/patrickmn/go-cache"
+)
 
 type Adapter interface {
-	Delete(string)
-	Get(string) (interface{}, bool)
-	IncrementInt(string, int) (int, error)
-	Set(string, interface{}, time.Duration)
+	Delete(context.Context, string) error
+	Get(context.Context, string) (interface{}, bool, error)
+	IncrementInt(context.Context, string, int64, time.Duration) (int64, error)
+	Set(context.Context, string, interface{}, time.Duration) error
+}
+
+type goCacheAdapter struct {
+	Cache *goCache.Cache
+}
+
+// NewGoCacheAdapter wraps an in memory go-cache so it implements Adapter
+func NewGoCacheAdapter(gocache *goCache.Cache) Adapter {
+	return &goCacheAdapter{
+		Cache: gocache,
+	}
+}
+
+func (a *goCacheAdapter) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	a.Cache.Delete(key)
+	return nil
+}
+
+func (a *goCacheAdapter) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	object, found := a.Cache.Get(key)
+	return object, found, nil
+}
+
+// IncrementInt creates the key with ttl when it doesn't exist, otherwise increments it, values are stored as int64
+func (a *goCacheAdapter) IncrementInt(ctx context.Context, key string, val int64, ttl time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := a.Cache.Add(key, val, ttl); err == nil {
+		return val, nil
+	}
+	return a.Cache.IncrementInt64(key, val)
+}
+
+func (a *goCacheAdapter) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	a.Cache.Set(key, value, ttl)
+	return nil
 }
@@ -0,0 +1,45 @@
+package circuitbreaker
+
+import "context"
+
+// Transitions reports what Record has changed
+// Warned is set when any level other than the trip level is latched, Levels lists every newly latched level
+type Transitions struct {
+	WindowValue int64
+	Tripped     bool
+	Warned      bool
+	Levels      []string
+}
+
+// Record updates latest buckets value and returns the new window value
+// when auto trip is enabled, every threshold level crossed is latched, by default warning alert and trip
+func (c *circuitBreaker) Record(ctx context.Context, amount int64) (Transitions, error) {
+	if !c.Active {
+		return Transitions{}, nil
+	}
+
+	if err := c.incrementLatestBuckets(ctx, amount); err != nil {
+		return Transitions{}, err
+	}
+
+	windowValue, err := c.CalculateWindowValue(ctx)
+	if err != nil {
+		return Transitions{}, err
+	}
+
+	transitions := Transitions{WindowValue: windowValue}
+	if !c.AutoTrip {
+		return transitions, nil
+	}
+
+	if err := c.latchLevels(ctx, windowValue, &transitions); err != nil {
+		return transitions, err
+	}
+
+	return transitions, nil
+}
+
+// SetAutoTrip sets whether crossing thresholds trips the circuit breaker automatically
+func (c *circuitBreaker) SetAutoTrip(autoTrip bool) {
+	c.AutoTrip = autoTrip
+}
@@ -0,0 +1,111 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+)
+
+func TestCircuitBreaker_Record(t *testing.T) {
+	type Request struct {
+		autoTrip         bool
+		threshold        int64
+		warningThreshold int64
+		amounts          []int64
+	}
+
+	type Response struct {
+		transitions []circuitbreaker.Transitions
+		isTripped   bool
+		isWarned    bool
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Record without auto trip only reports window value": {
+			request: Request{
+				autoTrip:         false,
+				threshold:        100,
+				warningThreshold: 50,
+				amounts:          []int64{60, 60},
+			},
+			response: Response{
+				transitions: []circuitbreaker.Transitions{
+					{WindowValue: 60},
+					{WindowValue: 120},
+				},
+			},
+		},
+		"Auto trip sets warning then trip once": {
+			request: Request{
+				autoTrip:         true,
+				threshold:        100,
+				warningThreshold: 50,
+				amounts:          []int64{30, 30, 30, 30, 30},
+			},
+			response: Response{
+				transitions: []circuitbreaker.Transitions{
+					{WindowValue: 30},
+					{WindowValue: 60, Warned: true, Levels: []string{"warning"}},
+					{WindowValue: 90},
+					{WindowValue: 120, Tripped: true, Levels: []string{"trip"}},
+					{WindowValue: 150},
+				},
+				isTripped: true,
+				isWarned:  true,
+			},
+		},
+		"Auto trip without warning threshold never warns": {
+			request: Request{
+				autoTrip:  true,
+				threshold: 100,
+				amounts:   []int64{100},
+			},
+			response: Response{
+				transitions: []circuitbreaker.Transitions{
+					{WindowValue: 100, Tripped: true, Levels: []string{"trip"}},
+				},
+				isTripped: true,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, client := newRedisClient(t)
+			cb, _ := newTestCircuitBreaker(t, client, "test", circuitbreaker.WithThreshold(tc.request.threshold))
+			cb.SetWarningThreshold(tc.request.warningThreshold)
+			cb.SetAutoTrip(tc.request.autoTrip)
+
+			for i, amount := range tc.request.amounts {
+				transitions, err := cb.Record(ctx, amount)
+				assert.Nil(t, err)
+				assert.Equal(t, tc.response.transitions[i], transitions)
+			}
+
+			isTripped, _ := cb.GetTrip(ctx)
+			assert.Equal(t, tc.response.isTripped, isTripped)
+			isWarned, _ := cb.GetTripWarning(ctx)
+			assert.Equal(t, tc.response.isWarned, isWarned)
+		})
+	}
+}
+
+func TestCircuitBreaker_UpdateLatestBucketsValueAutoTrip(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	cb, _ := newTestCircuitBreaker(t, client, "test", circuitbreaker.WithThreshold(100))
+	cb.SetAutoTrip(true)
+
+	assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, 100))
+
+	isTripped, err := cb.GetTrip(ctx)
+	assert.Nil(t, err)
+	assert.True(t, isTripped)
+}
@@ -1,7 +1,7 @@
 package circuitbreaker
 
 import (
-	"regexp"
+	"strings"
 	"time"
 )
 
@@ -19,6 +19,24 @@ func NewBucket(duration time.Duration) *Bucket {
 }
 
 func (c *Bucket) setName() {
-	re := regexp.MustCompile(ParseNameFromDurationRegex)
-	c.Name = re.FindString(c.Duration.String())
+	c.Name = durationName(c.Duration)
+}
+
+// durationName returns duration string without trailing zero units
+// days are kept in hours so existing keys stay stable
+// example:
+// 24h0m0s-> 24h
+// 1h30m0s-> 1h30m
+// 1m30s-> 1m30s
+// 30s-> 30s
+func durationName(duration time.Duration) string {
+	name := duration.String()
+	if strings.HasSuffix(name, "m0s") {
+		name = strings.TrimSuffix(name, "0s")
+	}
+	if strings.HasSuffix(name, "h0m") {
+		name = strings.TrimSuffix(name, "0m")
+	}
+
+	return name
 }
@@ -44,6 +44,61 @@ func TestBucket_NewBucket(t *testing.T) {
 				},
 			},
 		},
+		"NewBucket create thirty second bucket": {
+			request: Request{
+				duration: 30 * time.Second,
+			},
+			response: Response{
+				result: &circuitbreaker.Bucket{
+					Duration: 30 * time.Second,
+					Name:     "30s",
+				},
+			},
+		},
+		"NewBucket create mixed minute and second bucket": {
+			request: Request{
+				duration: 90 * time.Second,
+			},
+			response: Response{
+				result: &circuitbreaker.Bucket{
+					Duration: 90 * time.Second,
+					Name:     "1m30s",
+				},
+			},
+		},
+		"NewBucket create mixed hour and minute bucket": {
+			request: Request{
+				duration: 90 * time.Minute,
+			},
+			response: Response{
+				result: &circuitbreaker.Bucket{
+					Duration: 90 * time.Minute,
+					Name:     "1h30m",
+				},
+			},
+		},
+		"NewBucket create one day bucket": {
+			request: Request{
+				duration: 24 * time.Hour,
+			},
+			response: Response{
+				result: &circuitbreaker.Bucket{
+					Duration: 24 * time.Hour,
+					Name:     "24h",
+				},
+			},
+		},
+		"NewBucket create one week bucket": {
+			request: Request{
+				duration: 7 * 24 * time.Hour,
+			},
+			response: Response{
+				result: &circuitbreaker.Bucket{
+					Duration: 7 * 24 * time.Hour,
+					Name:     "168h",
+				},
+			},
+		},
 	}
 
 	for name, tc := range testcases {
@@ -1,68 +1,239 @@
 package circuitbreaker
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"sync"
 	"time"
 )
 
 //go:generate mockgen -destination=mock/cache_mock.go -package=mock --build_flags=--mod=mod go-circuit-breaker Cache
 
 var (
-	ErrCacheMiss = errors.New("cache miss")
+	ErrCacheMiss         = errors.New("cache miss")
+	ErrInvalidCacheValue = errors.New("invalid cache value")
 )
 
+// Cache is typed, so the circuit breaker never asserts interface{}, implementations convert stored values
+// single key reads return ErrCacheMiss when the key doesn't exist, GetInts leaves missing keys out
 type Cache interface {
-	Get(key string) (interface{}, error)
-	Set(key string, value interface{}, ttl time.Duration)
-	GetMulti(keys []string) interface{}
-	IncrementInt(key string, val int) (int, error)
+	GetBool(ctx context.Context, key string) (bool, error)
+	GetInts(ctx context.Context, keys []string) (map[string]int64, error)
+	GetString(ctx context.Context, key string) (string, error)
+	// IncrementInt returns ErrAmountOverflow, leaving key unchanged, when the result wouldn't fit int64
+	IncrementInt(ctx context.Context, key string, val int64, ttl time.Duration) (int64, error)
+	// IncrementIntIfBelow sums windowKeys and, only when sum + val is below threshold,
+	// increments every bucketKeys by val. Both steps happen atomically.
+	IncrementIntIfBelow(ctx context.Context, windowKeys []string, bucketKeys []string, val int64, threshold int64, ttl time.Duration) (bool, int64, error)
+	SetBool(ctx context.Context, key string, value bool, ttl time.Duration) error
+	SetString(ctx context.Context, key string, value string, ttl time.Duration) error
 }
 
 type cache struct {
 	Cache              Adapter
 	ExpirationDuration time.Duration
+
+	mutex sync.Mutex
 }
 
 func NewCache(
-	gocache Adapter,
+	adapter Adapter,
 	expirationDuration time.Duration,
 ) Cache {
 	return &cache{
-		Cache:              gocache,
+		Cache:              adapter,
 		ExpirationDuration: expirationDuration,
 	}
 }
 
-func (c *cache) Get(key string) (interface{}, error) {
-	object, err := c.Cache.Get(key)
-	if !err {
+func (c *cache) GetBool(ctx context.Context, key string) (bool, error) {
+	object, err := c.get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	return toBool(object)
+}
+
+// GetInts converts every found value with toInt, missing keys are left out of the result
+func (c *cache) GetInts(ctx context.Context, keys []string) (map[string]int64, error) {
+	result := make(map[string]int64)
+	for _, key := range keys {
+		object, found, err := c.Cache.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if !found || object == nil {
+			continue
+		}
+
+		value, err := toInt(object)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+
+	return result, nil
+}
+
+func (c *cache) GetString(ctx context.Context, key string) (string, error) {
+	object, err := c.get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	return toString(object)
+}
+
+// IncrementInt holds the same mutex as IncrementIntIfBelow, so the overflow check and the increment can't interleave
+func (c *cache) IncrementInt(ctx context.Context, key string, val int64, ttl time.Duration) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, err := c.checkIncrement(ctx, key, val); err != nil {
+		return 0, err
+	}
+
+	return c.Cache.IncrementInt(ctx, key, val, c.getTTL(ttl))
+}
+
+// IncrementIntIfBelow holds a mutex while summing and incrementing, so concurrent callers can't overshoot threshold
+// every bucket key is checked for overflow before any of them is incremented
+func (c *cache) IncrementIntIfBelow(ctx context.Context, windowKeys []string, bucketKeys []string, val int64, threshold int64, ttl time.Duration) (bool, int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	total := int64(0)
+	for _, key := range windowKeys {
+		object, found, err := c.Cache.Get(ctx, key)
+		if err != nil {
+			return false, 0, err
+		}
+		if !found {
+			continue
+		}
+		value, err := toInt(object)
+		if err != nil {
+			return false, 0, err
+		}
+		total = saturatingAdd(total, value)
+	}
+
+	if saturatingAdd(total, val) >= threshold {
+		return false, total, nil
+	}
+
+	for _, key := range bucketKeys {
+		if _, err := c.checkIncrement(ctx, key, val); err != nil {
+			return false, total, err
+		}
+	}
+	for _, key := range bucketKeys {
+		if _, err := c.Cache.IncrementInt(ctx, key, val, c.getTTL(ttl)); err != nil {
+			return false, total, err
+		}
+	}
+
+	return true, saturatingAdd(total, val), nil
+}
+
+func (c *cache) SetBool(ctx context.Context, key string, value bool, ttl time.Duration) error {
+	return c.Cache.Set(ctx, key, value, c.getTTL(ttl))
+}
+
+func (c *cache) SetString(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return c.Cache.Set(ctx, key, value, c.getTTL(ttl))
+}
+
+// checkIncrement returns the value of key after adding val, or ErrAmountOverflow when it wouldn't fit int64
+// the caller holds the mutex
+func (c *cache) checkIncrement(ctx context.Context, key string, val int64) (int64, error) {
+	object, found, err := c.Cache.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if !found || object == nil {
+		return val, nil
+	}
+
+	value, err := toInt(object)
+	if err != nil {
+		return 0, err
+	}
+	result, err := addInt64(value, val)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", err, key)
+	}
+
+	return result, nil
+}
+
+// get returns ErrCacheMiss when key is not found
+func (c *cache) get(ctx context.Context, key string) (interface{}, error) {
+	object, found, err := c.Cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
 		return nil, ErrCacheMiss
 	}
 
 	return object, nil
 }
 
-func (c *cache) Set(key string, value interface{}, ttl time.Duration) {
-	duration := 0 * time.Minute
+// getTTL falls back to ExpirationDuration when ttl is not set
+func (c *cache) getTTL(ttl time.Duration) time.Duration {
 	if ttl > 0 {
-		duration = ttl
-	} else {
-		duration = c.ExpirationDuration
+		return ttl
 	}
-	c.Cache.Set(key, value, duration)
+	return c.ExpirationDuration
 }
 
-func (c *cache) GetMulti(keys []string) interface{} {
-	result := make(map[string]interface{})
-	for _, key := range keys {
-		object, _ := c.Cache.Get(key)
-		if object != nil {
-			result[key] = object
+// toInt converts cached counter into int64
+func toInt(object interface{}) (int64, error) {
+	switch value := object.(type) {
+	case int:
+		return int64(value), nil
+	case int64:
+		return value, nil
+	case string:
+		result, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidCacheValue, value)
 		}
+		return result, nil
 	}
-	return result
+
+	return 0, ErrInvalidCacheValue
 }
 
-func (c *cache) IncrementInt(key string, val int) (int, error) {
-	return c.Cache.IncrementInt(key, val)
+// toBool converts cached flag into bool, redis stores bool as "1" / "0"
+func toBool(object interface{}) (bool, error) {
+	switch value := object.(type) {
+	case bool:
+		return value, nil
+	case string:
+		result, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, fmt.Errorf("%w: %q", ErrInvalidCacheValue, value)
+		}
+		return result, nil
+	}
+
+	return false, ErrInvalidCacheValue
+}
+
+// toString converts cached value into string, bool written before state machine existed becomes "true" / "false"
+func toString(object interface{}) (string, error) {
+	switch value := object.(type) {
+	case string:
+		return value, nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	}
+
+	return "", ErrInvalidCacheValue
 }
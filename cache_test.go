@@ -1,8 +1,10 @@
 package circuitbreaker_test
 
 import (
-	"errors"
+	"context"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -23,7 +25,7 @@ func TestCache_NewCache(t *testing.T) {
 	}{
 		"NewCache success": {
 			request: Request{
-				goCache:            goCache.New(5*time.Minute, 5*time.Minute),
+				goCache:            circuitbreaker.NewGoCacheAdapter(goCache.New(5*time.Minute, 5*time.Minute)),
 				expirationDuration: 5 * time.Minute,
 			},
 		},
@@ -39,180 +41,252 @@ func TestCache_NewCache(t *testing.T) {
 	}
 }
 
-func TestCache_Get(t *testing.T) {
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func TestCache_GetBool(t *testing.T) {
 	type Request struct {
-		goCache            circuitbreaker.Adapter
-		expirationDuration time.Duration
-		key                string
+		key   string
+		value interface{}
+		ctx   context.Context
 	}
 	type Response struct {
-		object interface{}
+		result bool
 		err    error
 	}
 
 	testcases := map[string]struct {
 		request  Request
 		response Response
-		preFunc  func(req Request, res Response)
-		postFunc func(req Request, res Response)
 	}{
-		"Get success": {
+		"GetBool success": {
 			request: Request{
-				goCache:            goCache.New(5*time.Minute, 5*time.Minute),
-				expirationDuration: 5 * time.Minute,
-				key:                "test-key",
+				key:   "test-key",
+				value: true,
 			},
 			response: Response{
-				object: 10,
-				err:    nil,
+				result: true,
 			},
-			preFunc: func(req Request, res Response) {
-				req.goCache.Set(req.key, 10, 1*time.Minute)
+		},
+		"GetBool parses string": {
+			request: Request{
+				key:   "test-key",
+				value: "1",
 			},
-			postFunc: func(req Request, res Response) {
-				req.goCache.Delete(req.key)
+			response: Response{
+				result: true,
 			},
 		},
 		"key not exist": {
 			request: Request{
-				goCache:            goCache.New(5*time.Minute, 5*time.Minute),
-				expirationDuration: 5 * time.Minute,
-				key:                "test-key",
+				key: "test-key",
 			},
 			response: Response{
-				object: nil,
-				err:    circuitbreaker.ErrCacheMiss,
+				err: circuitbreaker.ErrCacheMiss,
+			},
+		},
+		"invalid value": {
+			request: Request{
+				key:   "test-key",
+				value: 10,
+			},
+			response: Response{
+				err: circuitbreaker.ErrInvalidCacheValue,
+			},
+		},
+		"context canceled": {
+			request: Request{
+				key: "test-key",
+				ctx: canceledContext(),
+			},
+			response: Response{
+				err: context.Canceled,
 			},
-			preFunc:  func(req Request, res Response) {},
-			postFunc: func(req Request, res Response) {},
 		},
 	}
 
 	for name, tc := range testcases {
 		t.Run(name, func(t *testing.T) {
-			tc.preFunc(tc.request, tc.response)
+			adapter := circuitbreaker.NewGoCacheAdapter(goCache.New(5*time.Minute, 5*time.Minute))
+			if tc.request.value != nil {
+				adapter.Set(context.Background(), tc.request.key, tc.request.value, time.Minute)
+			}
 
-			cache := circuitbreaker.NewCache(tc.request.goCache, tc.request.expirationDuration)
-			object, err := cache.Get(tc.request.key)
-			if err != nil {
-				assert.Equal(t, tc.response.err, err)
-			} else {
-				assert.Equal(t, tc.response.object, object)
+			ctx := tc.request.ctx
+			if ctx == nil {
+				ctx = context.Background()
 			}
 
-			tc.postFunc(tc.request, tc.response)
+			cache := circuitbreaker.NewCache(adapter, 5*time.Minute)
+			result, err := cache.GetBool(ctx, tc.request.key)
+			assert.ErrorIs(t, err, tc.response.err)
+			assert.Equal(t, tc.response.result, result)
 		})
 	}
 }
 
-func TestCache_Set(t *testing.T) {
+func TestCache_GetString(t *testing.T) {
 	type Request struct {
-		goCache            circuitbreaker.Adapter
-		expirationDuration time.Duration
-		key                string
-		value              interface{}
-		ttl                time.Duration
+		key   string
+		value interface{}
+		ctx   context.Context
+	}
+	type Response struct {
+		result string
+		err    error
 	}
 
 	testcases := map[string]struct {
 		request  Request
-		postFunc func(req Request)
+		response Response
 	}{
-		"Set success": {
+		"GetString success": {
 			request: Request{
-				goCache:            goCache.New(5*time.Minute, 5*time.Minute),
-				expirationDuration: 5 * time.Minute,
-				key:                "test-key",
-				value:              10,
-				ttl:                time.Minute,
+				key:   "test-key",
+				value: "open:1683628920000",
 			},
-			postFunc: func(req Request) {
-				req.goCache.Delete(req.key)
+			response: Response{
+				result: "open:1683628920000",
 			},
 		},
-		"ttl is zero": {
+		"GetString converts bool": {
 			request: Request{
-				goCache:            goCache.New(5*time.Minute, 5*time.Minute),
-				expirationDuration: 5 * time.Minute,
-				key:                "test-key",
-				value:              10,
-				ttl:                0,
+				key:   "test-key",
+				value: true,
 			},
-			postFunc: func(req Request) {
-				req.goCache.Delete(req.key)
+			response: Response{
+				result: "true",
+			},
+		},
+		"key not exist": {
+			request: Request{
+				key: "test-key",
+			},
+			response: Response{
+				err: circuitbreaker.ErrCacheMiss,
+			},
+		},
+		"invalid value": {
+			request: Request{
+				key:   "test-key",
+				value: 10,
+			},
+			response: Response{
+				err: circuitbreaker.ErrInvalidCacheValue,
+			},
+		},
+		"context canceled": {
+			request: Request{
+				key: "test-key",
+				ctx: canceledContext(),
+			},
+			response: Response{
+				err: context.Canceled,
 			},
 		},
 	}
 
 	for name, tc := range testcases {
 		t.Run(name, func(t *testing.T) {
-			cache := circuitbreaker.NewCache(tc.request.goCache, tc.request.expirationDuration)
-			cache.Set(tc.request.key, tc.request.value, tc.request.ttl)
-			tc.postFunc(tc.request)
+			adapter := circuitbreaker.NewGoCacheAdapter(goCache.New(5*time.Minute, 5*time.Minute))
+			if tc.request.value != nil {
+				adapter.Set(context.Background(), tc.request.key, tc.request.value, time.Minute)
+			}
+
+			ctx := tc.request.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			cache := circuitbreaker.NewCache(adapter, 5*time.Minute)
+			result, err := cache.GetString(ctx, tc.request.key)
+			assert.ErrorIs(t, err, tc.response.err)
+			assert.Equal(t, tc.response.result, result)
 		})
 	}
 }
 
-func TestCache_GetMulti(t *testing.T) {
+func TestCache_SetBool(t *testing.T) {
+	ctx := context.Background()
+	adapter := circuitbreaker.NewGoCacheAdapter(goCache.New(5*time.Minute, 5*time.Minute))
+	cache := circuitbreaker.NewCache(adapter, 5*time.Minute)
+
+	assert.Nil(t, cache.SetBool(ctx, "test-key", true, 0))
+
+	result, err := cache.GetBool(ctx, "test-key")
+	assert.Nil(t, err)
+	assert.True(t, result)
+}
+
+func TestCache_SetString(t *testing.T) {
+	ctx := context.Background()
+	adapter := circuitbreaker.NewGoCacheAdapter(goCache.New(5*time.Minute, 5*time.Minute))
+	cache := circuitbreaker.NewCache(adapter, 5*time.Minute)
+
+	assert.Nil(t, cache.SetString(ctx, "test-key", "closed:1683628920000", time.Minute))
+
+	result, err := cache.GetString(ctx, "test-key")
+	assert.Nil(t, err)
+	assert.Equal(t, "closed:1683628920000", result)
+}
+
+func TestCache_GetInts(t *testing.T) {
 	type Request struct {
-		goCache            circuitbreaker.Adapter
-		expirationDuration time.Duration
-		keys               []string
+		keys   []string
+		values map[string]interface{}
 	}
 
 	type Response struct {
-		result interface{}
+		result map[string]int64
+		err    error
 	}
 
 	testcases := map[string]struct {
 		request  Request
 		response Response
-		preFunc  func(req Request, res Response)
-		postFunc func(req Request, res Response)
 	}{
-		"GetMulti success": {
+		"GetInts success": {
 			request: Request{
-				goCache:            goCache.New(5*time.Minute, 5*time.Minute),
-				expirationDuration: 5 * time.Minute,
-				keys:               []string{"test-key"},
+				keys:   []string{"test-key-1", "test-key-2", "test-key-3", "test-key-4"},
+				values: map[string]interface{}{"test-key-1": 10, "test-key-2": int64(20), "test-key-4": "40"},
 			},
 			response: Response{
-				result: map[string]interface{}{"test-key": 10},
+				result: map[string]int64{"test-key-1": 10, "test-key-2": 20, "test-key-4": 40},
 			},
-			preFunc: func(req Request, res Response) {
-				for _, k := range req.keys {
-					req.goCache.Set(k, 10, 1*time.Minute)
-				}
+		},
+		"keys not exist": {
+			request: Request{
+				keys: []string{"test-key"},
 			},
-			postFunc: func(req Request, res Response) {
-				for _, k := range req.keys {
-					req.goCache.Delete(k)
-				}
+			response: Response{
+				result: map[string]int64{},
 			},
 		},
-		"keys not exist": {
+		"value is not a number": {
 			request: Request{
-				goCache:            goCache.New(5*time.Minute, 5*time.Minute),
-				expirationDuration: 5 * time.Minute,
-				keys:               []string{"test-key"},
+				keys:   []string{"test-key"},
+				values: map[string]interface{}{"test-key": "abc"},
 			},
 			response: Response{
-				result: map[string]interface{}{},
+				err: circuitbreaker.ErrInvalidCacheValue,
 			},
-			preFunc:  func(req Request, res Response) {},
-			postFunc: func(req Request, res Response) {},
 		},
 	}
 
 	for name, tc := range testcases {
 		t.Run(name, func(t *testing.T) {
-			tc.preFunc(tc.request, tc.response)
-
-			cache := circuitbreaker.NewCache(tc.request.goCache, tc.request.expirationDuration)
-			object := cache.GetMulti(tc.request.keys)
-			assert.Equal(t, tc.response.result, object)
+			adapter := circuitbreaker.NewGoCacheAdapter(goCache.New(5*time.Minute, 5*time.Minute))
+			for key, value := range tc.request.values {
+				adapter.Set(context.Background(), key, value, time.Minute)
+			}
 
-			tc.postFunc(tc.request, tc.response)
+			cache := circuitbreaker.NewCache(adapter, 5*time.Minute)
+			result, err := cache.GetInts(context.Background(), tc.request.keys)
+			assert.ErrorIs(t, err, tc.response.err)
+			assert.Equal(t, tc.response.result, result)
 		})
 	}
 }
@@ -222,10 +296,10 @@ func TestCache_IncrementInt(t *testing.T) {
 		goCache            circuitbreaker.Adapter
 		expirationDuration time.Duration
 		key                string
-		val                int
+		val                int64
 	}
 	type Response struct {
-		result interface{}
+		result int64
 		err    error
 	}
 
@@ -237,7 +311,7 @@ func TestCache_IncrementInt(t *testing.T) {
 	}{
 		"IncrementInt success": {
 			request: Request{
-				goCache:            goCache.New(5*time.Minute, 5*time.Minute),
+				goCache:            circuitbreaker.NewGoCacheAdapter(goCache.New(5*time.Minute, 5*time.Minute)),
 				expirationDuration: 5 * time.Minute,
 				key:                "test-key",
 				val:                10,
@@ -247,22 +321,22 @@ func TestCache_IncrementInt(t *testing.T) {
 				err:    nil,
 			},
 			preFunc: func(req Request, res Response) {
-				req.goCache.Set(req.key, 10, 1*time.Minute)
+				req.goCache.Set(context.Background(), req.key, int64(10), 1*time.Minute)
 			},
 			postFunc: func(req Request, res Response) {
-				req.goCache.Delete(req.key)
+				req.goCache.Delete(context.Background(), req.key)
 			},
 		},
-		"key not exist": {
+		"key not exist will be created": {
 			request: Request{
-				goCache:            goCache.New(5*time.Minute, 5*time.Minute),
+				goCache:            circuitbreaker.NewGoCacheAdapter(goCache.New(5*time.Minute, 5*time.Minute)),
 				expirationDuration: 5 * time.Minute,
 				key:                "test-key",
 				val:                10,
 			},
 			response: Response{
-				result: 0,
-				err:    errors.New("Item test-key not found"),
+				result: 10,
+				err:    nil,
 			},
 			preFunc:  func(req Request, res Response) {},
 			postFunc: func(req Request, res Response) {},
@@ -274,14 +348,54 @@ func TestCache_IncrementInt(t *testing.T) {
 			tc.preFunc(tc.request, tc.response)
 
 			cache := circuitbreaker.NewCache(tc.request.goCache, tc.request.expirationDuration)
-			result, err := cache.IncrementInt(tc.request.key, tc.request.val)
-			if err != nil {
-				assert.Equal(t, tc.response.err.Error(), err.Error())
-			} else {
-				assert.Equal(t, tc.response.result, result)
-			}
+			result, err := cache.IncrementInt(context.Background(), tc.request.key, tc.request.val, time.Minute)
+			assert.Equal(t, tc.response.err, err)
+			assert.Equal(t, tc.response.result, result)
 
 			tc.postFunc(tc.request, tc.response)
 		})
 	}
 }
+
+func TestCache_IncrementIntIfBelow(t *testing.T) {
+	t.Run("concurrent callers never reach threshold", func(t *testing.T) {
+		ctx := context.Background()
+		cache := circuitbreaker.NewCache(circuitbreaker.NewGoCacheAdapter(goCache.New(5*time.Minute, 5*time.Minute)), 5*time.Minute)
+		keys := []string{"test-key-1h", "test-key-1m"}
+
+		var wg sync.WaitGroup
+		var allowedCount int32
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				allowed, _, err := cache.IncrementIntIfBelow(ctx, keys[:1], keys, 1, 50, time.Minute)
+				assert.Nil(t, err)
+				if allowed {
+					atomic.AddInt32(&allowedCount, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(49), allowedCount)
+		result, err := cache.GetInts(ctx, keys)
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]int64{"test-key-1h": 49, "test-key-1m": 49}, result)
+	})
+
+	t.Run("rejected amount is not written", func(t *testing.T) {
+		ctx := context.Background()
+		cache := circuitbreaker.NewCache(circuitbreaker.NewGoCacheAdapter(goCache.New(5*time.Minute, 5*time.Minute)), 5*time.Minute)
+
+		allowed, windowValue, err := cache.IncrementIntIfBelow(ctx, []string{"test-key"}, []string{"test-key"}, 60, 100, time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, int64(60), windowValue)
+
+		allowed, windowValue, err = cache.IncrementIntIfBelow(ctx, []string{"test-key"}, []string{"test-key"}, 60, 100, time.Minute)
+		assert.Nil(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, int64(60), windowValue)
+	})
+}
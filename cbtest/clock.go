@@ -0,0 +1,104 @@
+package cbtest
+
+import (
+	"sync"
+	"time"
+
+	circuitbreaker "go-circuit-breaker"
+)
+
+// Clock is a manual clock, time only moves when Advance or Set is called
+// functions scheduled with AfterFunc run inside Advance or Set once their time is reached
+type Clock struct {
+	now    time.Time
+	timers []*timer
+	mutex  sync.RWMutex
+}
+
+type timer struct {
+	clock *Clock
+	at    time.Time
+	f     func()
+}
+
+func NewClock(now time.Time) *Clock {
+	return &Clock{
+		now: now,
+	}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.now
+}
+
+// AfterFunc schedules f to run once the clock has moved duration forward
+func (c *Clock) AfterFunc(duration time.Duration, f func()) circuitbreaker.Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	t := &timer{clock: c, at: c.now.Add(duration), f: f}
+	c.timers = append(c.timers, t)
+
+	return t
+}
+
+// Advance moves the clock forward by duration
+func (c *Clock) Advance(duration time.Duration) {
+	c.mutex.Lock()
+	c.now = c.now.Add(duration)
+	due := c.popDueTimers()
+	c.mutex.Unlock()
+
+	runTimers(due)
+}
+
+// Set moves the clock to now
+func (c *Clock) Set(now time.Time) {
+	c.mutex.Lock()
+	c.now = now
+	due := c.popDueTimers()
+	c.mutex.Unlock()
+
+	runTimers(due)
+}
+
+// popDueTimers removes and returns timers whose time is reached, the mutex must be held
+func (c *Clock) popDueTimers() []*timer {
+	due := []*timer{}
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		due = append(due, t)
+	}
+	c.timers = pending
+
+	return due
+}
+
+// runTimers runs timers without holding the mutex, so they may read the clock
+func runTimers(timers []*timer) {
+	for _, t := range timers {
+		t.f()
+	}
+}
+
+func (t *timer) Stop() bool {
+	c := t.clock
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,16 +1,17 @@
 package circuitbreaker
 
 import (
+	"context"
 	"fmt"
 	"math"
-	"regexp"
 	"sort"
 	"time"
 )
 
 var (
+	// Deprecated: bucket and window names are no longer parsed with a regex, they drop trailing zero units instead
 	ParseNameFromDurationRegex = `^\d+(h|m)`
-	TimePointStrFormat         = "200601021504"
+	TimePointStrFormat         = "20060102150405"
 	DefaultBucket              = []*Bucket{
 		NewBucket(4 * time.Hour),
 		NewBucket(time.Hour),
@@ -18,40 +19,85 @@ var (
 		NewBucket(time.Minute),
 	}
 	WarningAlertKeyExpiration = time.Hour * 12
+	DefaultKeyPrefix          = "cb"
+	DefaultWindowDuration     = 24 * time.Hour
 )
 
 //go:generate mockgen -destination=mock/circuit_breaker_mock.go -package=mock --build_flags=--mod=mod go-circuit-breaker CircuitBreaker
 
 type CircuitBreaker interface {
-	CalculateWindowValue() int
+	Allow(ctx context.Context) (bool, error)
+	CalculateWindowValue(ctx context.Context) (int64, error)
+	Execute(ctx context.Context, amount int64, fn func(ctx context.Context) error) error
 	GenerateKeys(currentTime time.Time) []string
 	GetActive() bool
-	GetTrip() (bool, error)
-	GetTripWarning() (bool, error)
+	GetFailureRate(ctx context.Context) (float64, int, error)
+	GetFeatureName() string
+	GetLatency(ctx context.Context, p float64) (time.Duration, error)
+	GetLevelLatch(ctx context.Context, name string) (bool, error)
+	GetState(ctx context.Context) (State, error)
+	GetTrip(ctx context.Context) (bool, error)
+	GetTripWarning(ctx context.Context) (bool, error)
 	GetWindowDurationStr() string
-	IsExceedingThreshold(amount int) bool
-	IsExceedingWarningThreshold(amount int) bool
+	Guard(ctx context.Context, amount int64) error
+	IsExceedingLatency(ctx context.Context, p float64, target time.Duration) (bool, error)
+	IsExceedingThreshold(ctx context.Context, amount int64) (bool, error)
+	IsExceedingWarningThreshold(ctx context.Context, amount int64) (bool, error)
+	Level(ctx context.Context, amount int64) (string, error)
+	Record(ctx context.Context, amount int64) (Transitions, error)
+	RecordCall(ctx context.Context, success bool, amount int64) error
+	RecordFailure(ctx context.Context) error
+	RecordLatency(ctx context.Context, latency time.Duration) error
+	RecordResult(ctx context.Context, success bool) error
+	RecordSuccess(ctx context.Context) error
+	ReleaseProbe(ctx context.Context) error
+	Reserve(ctx context.Context, amount int64) (Reservation, error)
 	SetActive(active bool)
-	SetThreshold(threshold int)
-	SetWarningThreshold(threshold int)
-	UpdateLatestBucketsValue(amount int) error
-	UpdateTrip(isTripped bool)
-	UpdateTripWarning(isTripped bool)
+	SetAutoTrip(autoTrip bool)
+	SetLatencyConfig(config LatencyConfig)
+	SetLimitProvider(provider LimitProvider)
+	SetLookupStrategy(strategy LookupStrategy)
+	SetRatioConfig(config RatioConfig)
+	SetRecordPolicy(policy RecordPolicy)
+	SetReservationTimeout(timeout time.Duration)
+	SetStateMachineConfig(config StateMachineConfig)
+	SetThreshold(threshold int64)
+	SetThresholdLevels(levels []ThresholdLevel) error
+	SetWarningPercentage(percentage float64)
+	SetWarningThreshold(threshold int64)
+	TryConsume(ctx context.Context, amount int64) (bool, int64, error)
+	UpdateLatestBucketsValue(ctx context.Context, amount int64) error
+	UpdateTrip(ctx context.Context, isTripped bool) error
+	UpdateTripWarning(ctx context.Context, isTripped bool) error
 }
 
 type circuitBreaker struct {
-	Cache Cache
-
-	Active            bool
-	Buckets           []*Bucket
-	CacheTTL          time.Duration
-	FeatureName       string
-	Threshold         int
-	TripKey           string
-	WarningAlertKey   string
-	WarningThreshold  int
-	WindowDuration    time.Duration
-	WindowDurationStr string
+	Cache         Cache
+	Clock         Clock
+	LimitProvider LimitProvider
+
+	Active             bool
+	AutoTrip           bool
+	Buckets            []*Bucket
+	CacheTTL           time.Duration
+	Currency           Currency
+	FeatureName        string
+	KeyPrefix          string
+	LatencyConfig      LatencyConfig
+	LookupStrategy     LookupStrategy
+	RatioConfig        RatioConfig
+	RecordPolicy       RecordPolicy
+	ReservationTimeout time.Duration
+	Series             string
+	StateMachineConfig StateMachineConfig
+	Threshold          int64
+	ThresholdLevels    []ThresholdLevel
+	TripKey            string
+	WarningAlertKey    string
+	WarningPercentage  float64
+	WarningThreshold   int64
+	WindowDuration     time.Duration
+	WindowDurationStr  string
 }
 
 func NewCircuitBreaker(
@@ -60,78 +106,160 @@ func NewCircuitBreaker(
 	cacheTTL time.Duration,
 	featureName string,
 	windowDuration time.Duration,
+	opts ...Option,
 ) CircuitBreaker {
-	circuitBreaker := &circuitBreaker{
-		Cache: cache,
+	circuitBreaker := newCircuitBreaker(featureName)
+	circuitBreaker.Buckets = buckets
+	circuitBreaker.Cache = cache
+	circuitBreaker.CacheTTL = cacheTTL
+	circuitBreaker.WindowDuration = windowDuration
+
+	for _, opt := range opts {
+		opt(circuitBreaker)
+	}
+	circuitBreaker.init()
+
+	return circuitBreaker
+}
+
+// New creates circuit breaker from options and validates the result
+// window defaults to DefaultWindowDuration and buckets default to DefaultBucket
+// WithCurrency is rejected, amounts of New are not money, see NewMoneyBreaker
+func New(featureName string, opts ...Option) (CircuitBreaker, error) {
+	circuitBreaker := newCircuitBreakerFromOptions(featureName, opts...)
+	if err := circuitBreaker.validateWithoutCurrency(); err != nil {
+		return nil, err
+	}
+	circuitBreaker.init()
+
+	return circuitBreaker, nil
+}
+
+// newCircuitBreakerFromOptions applies opts on top of the defaults of New, the result is not validated yet
+func newCircuitBreakerFromOptions(featureName string, opts ...Option) *circuitBreaker {
+	circuitBreaker := newCircuitBreaker(featureName)
+	circuitBreaker.WindowDuration = DefaultWindowDuration
+
+	for _, opt := range opts {
+		opt(circuitBreaker)
+	}
+
+	return circuitBreaker
+}
 
-		Active:         true,
-		Buckets:        buckets,
-		CacheTTL:       cacheTTL,
-		FeatureName:    featureName,
-		Threshold:      math.MaxInt,
-		WindowDuration: windowDuration,
+// newCircuitBreaker creates circuit breaker with default values
+func newCircuitBreaker(featureName string) *circuitBreaker {
+	return &circuitBreaker{
+		Clock: NewRealClock(),
+
+		Active:             true,
+		FeatureName:        featureName,
+		KeyPrefix:          DefaultKeyPrefix,
+		ReservationTimeout: DefaultReservationTimeout,
+		StateMachineConfig: DefaultStateMachineConfig,
+		Threshold:          math.MaxInt64,
 	}
+}
 
-	if len(circuitBreaker.Buckets) == 0 {
-		circuitBreaker.Buckets = DefaultBucket
+// init sorts buckets and derives keys once every field is set
+func (c *circuitBreaker) init() {
+	if len(c.Buckets) == 0 {
+		c.Buckets = DefaultBucket
 	}
 
 	// sort buckets by the largest to smallest duration
-	sort.Slice(circuitBreaker.Buckets, func(i, j int) bool {
-		return circuitBreaker.Buckets[i].Duration > circuitBreaker.Buckets[j].Duration
+	sort.Slice(c.Buckets, func(i, j int) bool {
+		return c.Buckets[i].Duration > c.Buckets[j].Duration
 	})
 
-	circuitBreaker.setWindowDurationStr()
-	circuitBreaker.setTripKey()
-	circuitBreaker.setWarningAlertKey()
-
-	return circuitBreaker
+	c.setWindowDurationStr()
+	c.setTripKey()
+	c.setWarningAlertKey()
 }
 
-// CalculateWindowValue calculates sum of values within window duration
-func (c *circuitBreaker) CalculateWindowValue() int {
+// CalculateWindowValue calculates sum of values within window duration, the sum saturates instead of overflowing
+func (c *circuitBreaker) CalculateWindowValue(ctx context.Context) (int64, error) {
 	if !c.Active {
-		return math.MaxInt
+		return math.MaxInt64, nil
 	}
 
-	currentTime := time.Now().UTC()
-	results := c.Cache.GetMulti(c.GenerateKeys(currentTime))
-	cacheValues := results.(map[string]int)
+	currentTime := c.Clock.Now().UTC()
+	keys := c.GenerateKeys(currentTime)
+	cacheValues, err := c.Cache.GetInts(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+	if c.LookupStrategy == LookupSliding {
+		return c.sumWindowValues(cacheValues, keys, currentTime), nil
+	}
 
-	totalValue := 0
+	totalValue := int64(0)
 	for _, v := range cacheValues {
-		totalValue += v
+		totalValue = saturatingAdd(totalValue, v)
 	}
 
-	return totalValue
+	return totalValue, nil
 }
 
 // IsExceedingThreshold will check if current window value + amount has exceeded the threshold or not
-func (c *circuitBreaker) IsExceedingThreshold(amount int) bool {
+func (c *circuitBreaker) IsExceedingThreshold(ctx context.Context, amount int64) (bool, error) {
 	if !c.Active {
-		return false
+		return false, nil
 	}
 
-	return c.CalculateWindowValue()+amount >= c.Threshold
+	threshold, err := c.getThreshold(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return c.isExceeding(ctx, amount, threshold)
 }
 
-func (c *circuitBreaker) IsExceedingWarningThreshold(amount int) bool {
+// IsExceedingWarningThreshold will check if current window value + amount has exceeded the warning threshold or not
+func (c *circuitBreaker) IsExceedingWarningThreshold(ctx context.Context, amount int64) (bool, error) {
 	if !c.Active {
-		return false
+		return false, nil
 	}
 
-	return c.CalculateWindowValue()+amount >= c.WarningThreshold
+	warningThreshold, err := c.getWarningThreshold(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return c.isExceeding(ctx, amount, warningThreshold)
+}
+
+// isExceeding compares current window value + amount against threshold, the sum saturates so a huge amount always exceeds
+func (c *circuitBreaker) isExceeding(ctx context.Context, amount int64, threshold int64) (bool, error) {
+	if !c.Active {
+		return false, nil
+	}
+
+	windowValue, err := c.CalculateWindowValue(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return saturatingAdd(windowValue, amount) >= threshold, nil
 }
 
 // GenerateKeys will generate keys within window duration
 func (c *circuitBreaker) GenerateKeys(currentTime time.Time) []string {
+	switch c.LookupStrategy {
+	case LookupExact:
+		return c.generateExactKeys(currentTime)
+	case LookupSliding:
+		currentKey, previousKey, _ := c.getSlidingKeys(currentTime)
+		return []string{currentKey, previousKey}
+	}
+
 	result := []string{}
 
 	endTime := currentTime
 	startTime := currentTime.Add(-1 * c.WindowDuration)
 
 	endTime = endTime.Truncate(c.Buckets[0].Duration)
-	startTime = startTime.Truncate(time.Minute)
+	startTime = startTime.Truncate(c.Buckets[len(c.Buckets)-1].Duration)
 
 	// appending head key
 	result = append(result, c.getTimePointKey(c.Buckets[0].Name, endTime))
@@ -151,28 +279,37 @@ func (c *circuitBreaker) GetActive() bool {
 	return c.Active
 }
 
-// GetTrip retrieves trip from cache
-func (c *circuitBreaker) GetTrip() (bool, error) {
-	return c.getBoolCache(c.TripKey)
+// GetFeatureName returns the feature name every key is identified with
+func (c *circuitBreaker) GetFeatureName() string {
+	return c.FeatureName
+}
+
+// GetTrip retrieves trip from cache, circuit is tripped while it is Open or Half-Open
+func (c *circuitBreaker) GetTrip(ctx context.Context) (bool, error) {
+	if !c.Active {
+		return false, nil
+	}
+
+	value, err := c.getStateValue(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return value.State != StateClosed, nil
 }
 
 // GetTripWarning retrieves warning alert from cache
-func (c *circuitBreaker) GetTripWarning() (bool, error) {
-	return c.getBoolCache(c.WarningAlertKey)
+func (c *circuitBreaker) GetTripWarning(ctx context.Context) (bool, error) {
+	return c.getBoolCache(ctx, c.WarningAlertKey)
 }
 
 // getBoolCache retrieves bool value from cache with cacheKey
-func (c *circuitBreaker) getBoolCache(cacheKey string) (bool, error) {
+func (c *circuitBreaker) getBoolCache(ctx context.Context, cacheKey string) (bool, error) {
 	if !c.Active {
 		return false, nil
 	}
 
-	object, err := c.Cache.Get(cacheKey)
-	if err != nil {
-		return false, ErrCacheMiss
-	}
-
-	return object.(bool), nil
+	return c.Cache.GetBool(ctx, cacheKey)
 }
 
 // GetWindowDurationStr return the window duration in string
@@ -186,24 +323,69 @@ func (c *circuitBreaker) SetActive(active bool) {
 }
 
 // SetThreshold will set threshold for circuit breaker
-func (c *circuitBreaker) SetThreshold(threshold int) {
+func (c *circuitBreaker) SetThreshold(threshold int64) {
 	c.Threshold = threshold
 }
 
-func (c *circuitBreaker) SetWarningThreshold(threshold int) {
+func (c *circuitBreaker) SetWarningThreshold(threshold int64) {
 	c.WarningThreshold = threshold
 }
 
+// TryConsume checks the threshold and updates latest buckets value in one atomic step
+// returns whether amount is allowed and the window value after consuming
+func (c *circuitBreaker) TryConsume(ctx context.Context, amount int64) (bool, int64, error) {
+	if !c.Active {
+		return true, 0, nil
+	}
+
+	threshold, err := c.getThreshold(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+
+	now := c.Clock.Now().UTC()
+	return c.incrementIfBelow(ctx, now, c.getLatestBucketKeys(now), amount, threshold)
+}
+
+// incrementIfBelow increments bucketKeys by amount only when the window value stays below threshold
+// the previous window of LookupSliding is closed, nothing but releases touches it, so its weighted value is read first
+// and taken out of threshold, only the current window is summed inside the atomic check
+func (c *circuitBreaker) incrementIfBelow(ctx context.Context, now time.Time, bucketKeys []string, amount int64, threshold int64) (bool, int64, error) {
+	windowKeys := c.GenerateKeys(now)
+	if c.LookupStrategy != LookupSliding {
+		return c.Cache.IncrementIntIfBelow(ctx, windowKeys, bucketKeys, amount, threshold, c.CacheTTL)
+	}
+
+	cacheValues, err := c.Cache.GetInts(ctx, windowKeys[1:])
+	if err != nil {
+		return false, 0, err
+	}
+	previousValue := c.sumWindowValues(cacheValues, windowKeys, now)
+
+	allowed, total, err := c.Cache.IncrementIntIfBelow(ctx, windowKeys[:1], bucketKeys, amount, saturatingAdd(threshold, -previousValue), c.CacheTTL)
+	return allowed, saturatingAdd(total, previousValue), err
+}
+
 // UpdateLatestBucketsValue will update / create latest value
-func (c *circuitBreaker) UpdateLatestBucketsValue(amount int) error {
+// when auto trip is enabled, it also trips the circuit breaker once thresholds are crossed
+// ErrAmountOverflow is returned when amount would overflow a bucket
+func (c *circuitBreaker) UpdateLatestBucketsValue(ctx context.Context, amount int64) error {
 	if !c.Active {
 		return nil
 	}
 
-	now := time.Now().UTC()
-	for _, bucket := range c.Buckets {
-		timestamp := now.Truncate(bucket.Duration)
-		_, err := c.Cache.IncrementInt(c.getTimePointKey(bucket.Name, timestamp), amount)
+	if c.AutoTrip {
+		_, err := c.Record(ctx, amount)
+		return err
+	}
+
+	return c.incrementLatestBuckets(ctx, amount)
+}
+
+// incrementLatestBuckets increments the time point key of every bucket containing current time
+func (c *circuitBreaker) incrementLatestBuckets(ctx context.Context, amount int64) error {
+	for _, key := range c.getLatestBucketKeys(c.Clock.Now().UTC()) {
+		_, err := c.Cache.IncrementInt(ctx, key, amount, c.CacheTTL)
 		if err != nil {
 			return err
 		}
@@ -212,50 +394,79 @@ func (c *circuitBreaker) UpdateLatestBucketsValue(amount int) error {
 	return nil
 }
 
-// UpdateTrip updates circuit breaker trip (on/off)
+// getLatestBucketKeys returns the time point key of every bucket containing currentTime
+// with LookupSliding it is the current fixed window key only
+func (c *circuitBreaker) getLatestBucketKeys(currentTime time.Time) []string {
+	if c.LookupStrategy == LookupSliding {
+		currentKey, _, _ := c.getSlidingKeys(currentTime)
+		return []string{currentKey}
+	}
+
+	keys := make([]string, 0, len(c.Buckets))
+	for _, bucket := range c.Buckets {
+		keys = append(keys, c.getTimePointKey(bucket.Name, currentTime.Truncate(bucket.Duration)))
+	}
+
+	return keys
+}
+
+// UpdateTrip updates circuit breaker trip (on/off), tripping opens the circuit and releasing closes it
 // creates new key if doesn't exist
-func (c *circuitBreaker) UpdateTrip(isTripped bool) {
-	c.updateBoolCache(isTripped, c.TripKey, 0)
+func (c *circuitBreaker) UpdateTrip(ctx context.Context, isTripped bool) error {
+	if isTripped {
+		return c.setState(ctx, StateOpen, c.Clock.Now().UTC())
+	}
+	return c.setState(ctx, StateClosed, c.Clock.Now().UTC())
 }
 
 // UpdateTripWarning updates circuit breaker warning alert (on/off)
-// creates new key if doesn't exist
-func (c *circuitBreaker) UpdateTripWarning(isTripped bool) {
-	c.updateBoolCache(isTripped, c.WarningAlertKey, WarningAlertKeyExpiration)
+// creates new key if doesn't exist, it lives for CacheTTL
+// the warning level latched by auto trip expires after WarningAlertKeyExpiration instead
+func (c *circuitBreaker) UpdateTripWarning(ctx context.Context, isTripped bool) error {
+	return c.updateBoolCache(ctx, isTripped, c.WarningAlertKey, 0)
 }
 
 // updateBoolCache updates bool value with cacheKey (on/off)
-// creates new key if doesn't exist
-func (c *circuitBreaker) updateBoolCache(isTripped bool, cacheKey string, expiration time.Duration) {
+// creates new key if doesn't exist, it lives for expiration or CacheTTL when expiration is zero
+func (c *circuitBreaker) updateBoolCache(ctx context.Context, isTripped bool, cacheKey string, expiration time.Duration) error {
 	if !c.Active {
-		return
+		return nil
+	}
+	if expiration <= 0 {
+		expiration = c.CacheTTL
 	}
-	c.Cache.Set(cacheKey, isTripped, c.CacheTTL)
+	return c.Cache.SetBool(ctx, cacheKey, isTripped, expiration)
 }
 
-// getTimePointKey set key name with default format cb-<feature_name>-<window_duration_string>-<bucket>-<timestamp>
-// example: cb-loan_disbursement-24h-1m-202305101230
+// getTimePointKey set key name with default format <key_prefix>-<feature_name>-<window_duration_string>-<bucket>-<timestamp>
+// example: cb-loan_disbursement-24h-1m-20230510123000
+// a series adds its own segment after the feature name, see getSeries
 func (c *circuitBreaker) getTimePointKey(bucketName string, timestamp time.Time) string {
-	return fmt.Sprintf("cb-%s-%s-%s-%s", c.FeatureName, c.WindowDurationStr, bucketName, timestamp.Format(TimePointStrFormat))
+	featureName := c.FeatureName
+	if c.Series != "" {
+		featureName = fmt.Sprintf("%s-%s", c.FeatureName, c.Series)
+	}
+
+	return fmt.Sprintf("%s-%s-%s-%s-%s", c.KeyPrefix, featureName, c.WindowDurationStr, bucketName, timestamp.Format(TimePointStrFormat))
 }
 
-// setTripKey with format cb-trip-<feature_name>-<window_duration_string>
+// setTripKey with format <key_prefix>-trip-<feature_name>-<window_duration_string>
 // example: cb-trip-loan_disbursement-24h
 func (c *circuitBreaker) setTripKey() {
-	c.TripKey = fmt.Sprintf("cb-trip-%s-%s", c.FeatureName, c.WindowDurationStr)
+	c.TripKey = fmt.Sprintf("%s-trip-%s-%s", c.KeyPrefix, c.FeatureName, c.WindowDurationStr)
 }
 
-// setWarningAlertKey with format cb-warning-alert-<feature_name>-<window_duration_string>
+// setWarningAlertKey with format <key_prefix>-warning_alert-<feature_name>-<window_duration_string>
 // example: cb-warning_alert-loan_disbursement-24h
 func (c *circuitBreaker) setWarningAlertKey() {
-	c.WarningAlertKey = fmt.Sprintf("cb-warning_alert-%s-%s", c.FeatureName, c.WindowDurationStr)
+	c.WarningAlertKey = fmt.Sprintf("%s-warning_alert-%s-%s", c.KeyPrefix, c.FeatureName, c.WindowDurationStr)
 }
 
 // setWindowDurationStr will set WindowDurationStr from WindowDuration
 // example:
 // 24h0m0s-> 24h
-// 24m0s-> 24m
+// 1h30m0s-> 1h30m
+// 30s-> 30s
 func (c *circuitBreaker) setWindowDurationStr() {
-	re := regexp.MustCompile(ParseNameFromDurationRegex)
-	c.WindowDurationStr = re.FindString(c.WindowDuration.String())
+	c.WindowDurationStr = durationName(c.WindowDuration)
 }
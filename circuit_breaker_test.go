@@ -26,7 +26,7 @@ func TestCircuitBreaker_NewCircuitBreaker(t *testing.T) {
 		buckets        []*circuitbreaker.Bucket
 		cacheTTL       time.Duration
 		featureName    string
-		threshold      int
+		threshold      int64
 		windowDuration time.Duration
 	}
 
@@ -88,12 +88,13 @@ func TestCircuitBreaker_CalculateWindowValue(t *testing.T) {
 		buckets        []*circuitbreaker.Bucket
 		cacheTTL       time.Duration
 		featureName    string
-		threshold      int
+		threshold      int64
 		windowDuration time.Duration
 	}
 
 	type Response struct {
-		result int
+		result int64
+		err    error
 	}
 
 	testcases := map[string]struct {
@@ -120,10 +121,10 @@ func TestCircuitBreaker_CalculateWindowValue(t *testing.T) {
 				result: 80000,
 			},
 			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
-				resMap := make(map[string]int)
+				resMap := make(map[string]int64)
 				resMap["cb-test-4h-202305100800"] = 50000
 				resMap["cb-test-4h-202305101200"] = 30000
-				m.Cache.EXPECT().GetMulti(gomock.Any()).Return(resMap)
+				m.Cache.EXPECT().GetInts(gomock.Any(), gomock.Any()).Return(resMap, nil)
 			},
 		},
 		"when circuit breaker is inactive then return MaxInt": {
@@ -165,7 +166,30 @@ func TestCircuitBreaker_CalculateWindowValue(t *testing.T) {
 				result: 0,
 			},
 			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
-				m.Cache.EXPECT().GetMulti(gomock.Any()).Return(make(map[string]int))
+				m.Cache.EXPECT().GetInts(gomock.Any(), gomock.Any()).Return(make(map[string]int64), nil)
+			},
+		},
+		"GetInts returns error": {
+			request: Request{
+				ctx:    context.Background(),
+				active: true,
+				buckets: []*circuitbreaker.Bucket{
+					circuitbreaker.NewBucket(4 * time.Hour),
+					circuitbreaker.NewBucket(1 * time.Hour),
+					circuitbreaker.NewBucket(5 * time.Minute),
+					circuitbreaker.NewBucket(1 * time.Minute),
+				},
+				cacheTTL:       24 * time.Hour,
+				featureName:    "test",
+				threshold:      100000,
+				windowDuration: 24 * time.Hour,
+			},
+			response: Response{
+				result: 0,
+				err:    ErrUnexpectedRedis,
+			},
+			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
+				m.Cache.EXPECT().GetInts(gomock.Any(), gomock.Any()).Return(nil, ErrUnexpectedRedis)
 			},
 		},
 	}
@@ -186,7 +210,8 @@ func TestCircuitBreaker_CalculateWindowValue(t *testing.T) {
 			)
 			cb.SetActive(tc.request.active)
 
-			result := cb.CalculateWindowValue()
+			result, err := cb.CalculateWindowValue(tc.request.ctx)
+			assert.Equal(t, tc.response.err, err)
 			assert.Equal(t, tc.response.result, result)
 		})
 	}
@@ -195,18 +220,19 @@ func TestCircuitBreaker_CalculateWindowValue(t *testing.T) {
 func TestCircuitBreaker_IsExceedingThreshold(t *testing.T) {
 	type Request struct {
 		ctx    context.Context
-		amount int
+		amount int64
 
 		active         bool
 		buckets        []*circuitbreaker.Bucket
 		cacheTTL       time.Duration
 		featureName    string
-		threshold      int
+		threshold      int64
 		windowDuration time.Duration
 	}
 
 	type Response struct {
 		result bool
+		err    error
 	}
 
 	testcases := map[string]struct {
@@ -231,10 +257,10 @@ func TestCircuitBreaker_IsExceedingThreshold(t *testing.T) {
 				result: false,
 			},
 			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
-				resMap := make(map[string]int)
+				resMap := make(map[string]int64)
 				resMap["cb-test-4h-202305100800"] = 50000
 				resMap["cb-test-4h-202305101200"] = 10000
-				m.Cache.EXPECT().GetMulti(gomock.Any()).Return(resMap)
+				m.Cache.EXPECT().GetInts(gomock.Any(), gomock.Any()).Return(resMap, nil)
 			},
 		},
 		"When circuit breaker is inactive, return false": {
@@ -272,10 +298,31 @@ func TestCircuitBreaker_IsExceedingThreshold(t *testing.T) {
 				result: true,
 			},
 			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
-				resMap := make(map[string]int)
+				resMap := make(map[string]int64)
 				resMap["cb-test-4h-202305100800"] = 50000
 				resMap["cb-test-4h-202305101200"] = 60000
-				m.Cache.EXPECT().GetMulti(gomock.Any()).Return(resMap)
+				m.Cache.EXPECT().GetInts(gomock.Any(), gomock.Any()).Return(resMap, nil)
+			},
+		},
+		"When cache fails, return the error": {
+			request: Request{
+				ctx:    context.Background(),
+				amount: 20000,
+				active: true,
+				buckets: []*circuitbreaker.Bucket{
+					circuitbreaker.NewBucket(24 * time.Hour),
+				},
+				cacheTTL:       24 * time.Hour,
+				featureName:    "test",
+				threshold:      100000,
+				windowDuration: 24 * time.Hour,
+			},
+			response: Response{
+				result: false,
+				err:    ErrUnexpectedRedis,
+			},
+			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
+				m.Cache.EXPECT().GetInts(gomock.Any(), gomock.Any()).Return(nil, ErrUnexpectedRedis)
 			},
 		},
 	}
@@ -298,7 +345,8 @@ func TestCircuitBreaker_IsExceedingThreshold(t *testing.T) {
 			cb.SetActive(tc.request.active)
 			cb.SetThreshold(tc.request.threshold)
 
-			result := cb.IsExceedingThreshold(tc.request.amount)
+			result, err := cb.IsExceedingThreshold(tc.request.ctx, tc.request.amount)
+			assert.Equal(t, tc.response.err, err)
 			assert.Equal(t, tc.response.result, result)
 		})
 	}
@@ -307,13 +355,13 @@ func TestCircuitBreaker_IsExceedingThreshold(t *testing.T) {
 func TestCircuitBreaker_IsExceedingWarningThreshold(t *testing.T) {
 	type Request struct {
 		ctx    context.Context
-		amount int
+		amount int64
 
 		active           bool
 		buckets          []*circuitbreaker.Bucket
 		cacheTTL         time.Duration
 		featureName      string
-		warningThreshold int
+		warningThreshold int64
 		windowDuration   time.Duration
 	}
 
@@ -343,10 +391,10 @@ func TestCircuitBreaker_IsExceedingWarningThreshold(t *testing.T) {
 				result: false,
 			},
 			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
-				resMap := make(map[string]int)
+				resMap := make(map[string]int64)
 				resMap["cb-test-4h-202305100800"] = 50000
 				resMap["cb-test-4h-202305101200"] = 10000
-				m.Cache.EXPECT().GetMulti(gomock.Any()).Return(resMap)
+				m.Cache.EXPECT().GetInts(gomock.Any(), gomock.Any()).Return(resMap, nil)
 			},
 		},
 		"When circuit breaker is inactive, return false": {
@@ -384,10 +432,10 @@ func TestCircuitBreaker_IsExceedingWarningThreshold(t *testing.T) {
 				result: true,
 			},
 			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
-				resMap := make(map[string]int)
+				resMap := make(map[string]int64)
 				resMap["cb-test-4h-202305100800"] = 50000
 				resMap["cb-test-4h-202305101200"] = 60000
-				m.Cache.EXPECT().GetMulti(gomock.Any()).Return(resMap)
+				m.Cache.EXPECT().GetInts(gomock.Any(), gomock.Any()).Return(resMap, nil)
 			},
 		},
 	}
@@ -410,7 +458,8 @@ func TestCircuitBreaker_IsExceedingWarningThreshold(t *testing.T) {
 			cb.SetActive(tc.request.active)
 			cb.SetWarningThreshold(tc.request.warningThreshold)
 
-			result := cb.IsExceedingWarningThreshold(tc.request.amount)
+			result, err := cb.IsExceedingWarningThreshold(tc.request.ctx, tc.request.amount)
+			assert.Nil(t, err)
 			assert.Equal(t, tc.response.result, result)
 		})
 	}
@@ -424,7 +473,7 @@ func TestCircuitBreaker_GenerateKeys(t *testing.T) {
 		buckets        []*circuitbreaker.Bucket
 		cacheTTL       time.Duration
 		featureName    string
-		threshold      int
+		threshold      int64
 		windowDuration time.Duration
 	}
 
@@ -454,25 +503,25 @@ func TestCircuitBreaker_GenerateKeys(t *testing.T) {
 			},
 			response: Response{
 				result: []string{
-					"cb-test-24h-4h-202305120800",
-					"cb-test-24h-4h-202305120400",
-					"cb-test-24h-4h-202305120000",
-					"cb-test-24h-4h-202305112000",
-					"cb-test-24h-4h-202305111600",
-					"cb-test-24h-4h-202305111200",
-					"cb-test-24h-1h-202305111100",
-					"cb-test-24h-5m-202305111055",
-					"cb-test-24h-5m-202305111050",
-					"cb-test-24h-5m-202305111045",
-					"cb-test-24h-5m-202305111040",
-					"cb-test-24h-5m-202305111035",
-					"cb-test-24h-5m-202305111030",
-					"cb-test-24h-5m-202305111025",
-					"cb-test-24h-5m-202305111020",
-					"cb-test-24h-5m-202305111015",
-					"cb-test-24h-1m-202305111014",
-					"cb-test-24h-1m-202305111013",
-					"cb-test-24h-1m-202305111012",
+					"cb-test-24h-4h-20230512080000",
+					"cb-test-24h-4h-20230512040000",
+					"cb-test-24h-4h-20230512000000",
+					"cb-test-24h-4h-20230511200000",
+					"cb-test-24h-4h-20230511160000",
+					"cb-test-24h-4h-20230511120000",
+					"cb-test-24h-1h-20230511110000",
+					"cb-test-24h-5m-20230511105500",
+					"cb-test-24h-5m-20230511105000",
+					"cb-test-24h-5m-20230511104500",
+					"cb-test-24h-5m-20230511104000",
+					"cb-test-24h-5m-20230511103500",
+					"cb-test-24h-5m-20230511103000",
+					"cb-test-24h-5m-20230511102500",
+					"cb-test-24h-5m-20230511102000",
+					"cb-test-24h-5m-20230511101500",
+					"cb-test-24h-1m-20230511101400",
+					"cb-test-24h-1m-20230511101300",
+					"cb-test-24h-1m-20230511101200",
 				},
 			},
 		},
@@ -491,8 +540,74 @@ func TestCircuitBreaker_GenerateKeys(t *testing.T) {
 			},
 			response: Response{
 				result: []string{
-					"cb-test-24h-24h-202305120000",
-					"cb-test-24h-24h-202305110000",
+					"cb-test-24h-24h-20230512000000",
+					"cb-test-24h-24h-20230511000000",
+				},
+			},
+		},
+		"GenerateKeys with second buckets success": {
+			request: Request{
+				ctx:         context.Background(),
+				currentTime: time.Date(2023, time.May, 12, 10, 12, 45, 0, time.UTC),
+				active:      true,
+				buckets: []*circuitbreaker.Bucket{
+					circuitbreaker.NewBucket(1 * time.Minute),
+					circuitbreaker.NewBucket(30 * time.Second),
+				},
+				cacheTTL:       72 * time.Hour,
+				featureName:    "test",
+				threshold:      100000,
+				windowDuration: 2 * time.Minute,
+			},
+			response: Response{
+				result: []string{
+					"cb-test-2m-1m-20230512101200",
+					"cb-test-2m-1m-20230512101100",
+					"cb-test-2m-30s-20230512101030",
+				},
+			},
+		},
+		"GenerateKeys with mixed window success": {
+			request: Request{
+				ctx:         context.Background(),
+				currentTime: time.Date(2023, time.May, 12, 10, 30, 0, 0, time.UTC),
+				active:      true,
+				buckets: []*circuitbreaker.Bucket{
+					circuitbreaker.NewBucket(1 * time.Hour),
+					circuitbreaker.NewBucket(30 * time.Minute),
+				},
+				cacheTTL:       72 * time.Hour,
+				featureName:    "test",
+				threshold:      100000,
+				windowDuration: 90 * time.Minute,
+			},
+			response: Response{
+				result: []string{
+					"cb-test-1h30m-1h-20230512100000",
+					"cb-test-1h30m-1h-20230512090000",
+				},
+			},
+		},
+		"GenerateKeys with day buckets success": {
+			request: Request{
+				ctx:         context.Background(),
+				currentTime: time.Date(2023, time.May, 12, 22, 0, 0, 0, time.UTC),
+				active:      true,
+				buckets: []*circuitbreaker.Bucket{
+					circuitbreaker.NewBucket(24 * time.Hour),
+					circuitbreaker.NewBucket(1 * time.Hour),
+				},
+				cacheTTL:       72 * time.Hour,
+				featureName:    "test",
+				threshold:      100000,
+				windowDuration: 48 * time.Hour,
+			},
+			response: Response{
+				result: []string{
+					"cb-test-48h-24h-20230512000000",
+					"cb-test-48h-24h-20230511000000",
+					"cb-test-48h-1h-20230510230000",
+					"cb-test-48h-1h-20230510220000",
 				},
 			},
 		},
@@ -526,7 +641,7 @@ func TestCircuitBreaker_GetActive(t *testing.T) {
 		active         bool
 		cacheTTL       time.Duration
 		featureName    string
-		threshold      int
+		threshold      int64
 		windowDuration time.Duration
 	}
 
@@ -606,7 +721,7 @@ func TestCircuitBreaker_GetTrip(t *testing.T) {
 		active         bool
 		cacheTTL       time.Duration
 		featureName    string
-		threshold      int
+		threshold      int64
 		windowDuration time.Duration
 	}
 
@@ -640,7 +755,7 @@ func TestCircuitBreaker_GetTrip(t *testing.T) {
 				err:    nil,
 			},
 			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
-				m.Cache.EXPECT().Get("cb-trip-test-24h").Return(true, nil)
+				m.Cache.EXPECT().GetString(gomock.Any(), "cb-trip-test-24h").Return("true", nil)
 			},
 		},
 		"When circuit breaker is inactive, return false": {
@@ -685,7 +800,7 @@ func TestCircuitBreaker_GetTrip(t *testing.T) {
 				err:    "cache miss",
 			},
 			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
-				m.Cache.EXPECT().Get("cb-trip-test-24h").Return(false, circuitbreaker.ErrCacheMiss)
+				m.Cache.EXPECT().GetString(gomock.Any(), "cb-trip-test-24h").Return("", circuitbreaker.ErrCacheMiss)
 			},
 		},
 	}
@@ -707,7 +822,7 @@ func TestCircuitBreaker_GetTrip(t *testing.T) {
 			)
 			cb.SetActive(tc.request.active)
 
-			result, err := cb.GetTrip()
+			result, err := cb.GetTrip(tc.request.ctx)
 			if err != nil {
 				assert.Equal(t, tc.response.err, err.Error())
 			} else {
@@ -725,7 +840,7 @@ func TestCircuitBreaker_GetTripWarning(t *testing.T) {
 		active         bool
 		cacheTTL       time.Duration
 		featureName    string
-		threshold      int
+		threshold      int64
 		windowDuration time.Duration
 	}
 
@@ -759,7 +874,7 @@ func TestCircuitBreaker_GetTripWarning(t *testing.T) {
 				err:    nil,
 			},
 			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
-				m.Cache.EXPECT().Get("cb-warning_alert-test-24h").Return(true, nil)
+				m.Cache.EXPECT().GetBool(gomock.Any(), "cb-warning_alert-test-24h").Return(true, nil)
 			},
 		},
 		"When circuit breaker is inactive, return false": {
@@ -804,7 +919,7 @@ func TestCircuitBreaker_GetTripWarning(t *testing.T) {
 				err:    "cache miss",
 			},
 			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
-				m.Cache.EXPECT().Get("cb-warning_alert-test-24h").Return(false, circuitbreaker.ErrCacheMiss)
+				m.Cache.EXPECT().GetBool(gomock.Any(), "cb-warning_alert-test-24h").Return(false, circuitbreaker.ErrCacheMiss)
 			},
 		},
 	}
@@ -826,7 +941,7 @@ func TestCircuitBreaker_GetTripWarning(t *testing.T) {
 			)
 			cb.SetActive(tc.request.active)
 
-			result, err := cb.GetTripWarning()
+			result, err := cb.GetTripWarning(tc.request.ctx)
 			if err != nil {
 				assert.Equal(t, tc.response.err, err.Error())
 			} else {
@@ -861,13 +976,13 @@ func TestCircuitBreaker_GetWindowDurationStr(t *testing.T) {
 func TestCircuitBreaker_UpdateLatestBucketsValue(t *testing.T) {
 	type Request struct {
 		ctx    context.Context
-		amount int
+		amount int64
 
 		active         bool
 		buckets        []*circuitbreaker.Bucket
 		cacheTTL       time.Duration
 		featureName    string
-		threshold      int
+		threshold      int64
 		windowDuration time.Duration
 	}
 
@@ -897,7 +1012,7 @@ func TestCircuitBreaker_UpdateLatestBucketsValue(t *testing.T) {
 				err: nil,
 			},
 			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
-				m.Cache.EXPECT().IncrementInt(testutil.Regexp(`^cb-\w+-\d+(m|h)-\d+(m|h)-\d{12}$`), req.amount).Return(req.amount, nil)
+				m.Cache.EXPECT().IncrementInt(gomock.Any(), testutil.Regexp(`^cb-\w+-\d+(m|h)-\d+(m|h)-\d{14}$`), req.amount, req.cacheTTL).Return(req.amount, nil)
 			},
 		},
 		"When circuit breaker is inactive, wont update value": {
@@ -936,7 +1051,7 @@ func TestCircuitBreaker_UpdateLatestBucketsValue(t *testing.T) {
 				err: "some error",
 			},
 			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
-				m.Cache.EXPECT().IncrementInt(testutil.Regexp(`^cb-\w+-\d+(m|h)-\d+(m|h)-\d{12}$`), req.amount).Return(0, errors.New("some error"))
+				m.Cache.EXPECT().IncrementInt(gomock.Any(), testutil.Regexp(`^cb-\w+-\d+(m|h)-\d+(m|h)-\d{14}$`), req.amount, req.cacheTTL).Return(int64(0), errors.New("some error"))
 			},
 		},
 	}
@@ -958,7 +1073,7 @@ func TestCircuitBreaker_UpdateLatestBucketsValue(t *testing.T) {
 			)
 			cb.SetActive(tc.request.active)
 
-			err := cb.UpdateLatestBucketsValue(tc.request.amount)
+			err := cb.UpdateLatestBucketsValue(tc.request.ctx, tc.request.amount)
 			if err != nil {
 				assert.Equal(t, tc.response.err, err.Error())
 			}
@@ -973,7 +1088,7 @@ func TestCircuitBreaker_SetActive(t *testing.T) {
 		buckets        []*circuitbreaker.Bucket
 		cacheTTL       time.Duration
 		featureName    string
-		threshold      int
+		threshold      int64
 		windowDuration time.Duration
 	}
 
@@ -1028,7 +1143,7 @@ func TestCircuitBreaker_SetThreshold(t *testing.T) {
 		buckets        []*circuitbreaker.Bucket
 		cacheTTL       time.Duration
 		featureName    string
-		threshold      int
+		threshold      int64
 		windowDuration time.Duration
 	}
 
@@ -1083,7 +1198,7 @@ func TestCircuitBreaker_SetWarningThreshold(t *testing.T) {
 		buckets          []*circuitbreaker.Bucket
 		cacheTTL         time.Duration
 		featureName      string
-		warningThreshold int
+		warningThreshold int64
 		windowDuration   time.Duration
 	}
 
@@ -1141,7 +1256,7 @@ func TestCircuitBreaker_UpdateTrip(t *testing.T) {
 		buckets        []*circuitbreaker.Bucket
 		cacheTTL       time.Duration
 		featureName    string
-		threshold      int
+		threshold      int64
 		windowDuration time.Duration
 	}
 
@@ -1172,7 +1287,28 @@ func TestCircuitBreaker_UpdateTrip(t *testing.T) {
 				err: nil,
 			},
 			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
-				m.Cache.EXPECT().Set(req.key, req.isTripped, gomock.Any())
+				m.Cache.EXPECT().SetString(gomock.Any(), req.key, testutil.Regexp(`^open:\d+$`), req.cacheTTL).Return(nil)
+			},
+		},
+		"UpdateTrip false closes the circuit": {
+			request: Request{
+				ctx:       context.Background(),
+				key:       "cb-trip-test_window-168h",
+				isTripped: false,
+				active:    true,
+				buckets: []*circuitbreaker.Bucket{
+					circuitbreaker.NewBucket(4 * time.Hour),
+				},
+				cacheTTL:       24 * time.Hour,
+				featureName:    "test_window",
+				threshold:      100000,
+				windowDuration: 168 * time.Hour,
+			},
+			response: Response{
+				err: nil,
+			},
+			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
+				m.Cache.EXPECT().SetString(gomock.Any(), req.key, testutil.Regexp(`^closed:\d+$`), req.cacheTTL).Return(nil)
 			},
 		},
 		"When cb is inactive cache wont be set": {
@@ -1214,8 +1350,8 @@ func TestCircuitBreaker_UpdateTrip(t *testing.T) {
 			)
 			cb.SetActive(tc.request.active)
 
-			cb.UpdateTrip(tc.request.isTripped)
-			assert.Equal(t, tc.response.err, nil)
+			err := cb.UpdateTrip(tc.request.ctx, tc.request.isTripped)
+			assert.Equal(t, tc.response.err, err)
 		})
 	}
 }
@@ -1230,7 +1366,7 @@ func TestCircuitBreaker_UpdateTripWarning(t *testing.T) {
 		buckets        []*circuitbreaker.Bucket
 		cacheTTL       time.Duration
 		featureName    string
-		threshold      int
+		threshold      int64
 		windowDuration time.Duration
 	}
 
@@ -1253,7 +1389,7 @@ func TestCircuitBreaker_UpdateTripWarning(t *testing.T) {
 				windowDuration: 168 * time.Hour,
 			},
 			mockFn: func(m *fixture.MockCircuitBreaker, req Request) {
-				m.Cache.EXPECT().Set(req.key, req.isTripped, gomock.Any())
+				m.Cache.EXPECT().SetBool(gomock.Any(), req.key, req.isTripped, req.cacheTTL).Return(nil)
 			},
 		},
 	}
@@ -1275,8 +1411,152 @@ func TestCircuitBreaker_UpdateTripWarning(t *testing.T) {
 			)
 			cb.SetActive(tc.request.active)
 
-			cb.UpdateTripWarning(tc.request.isTripped)
-			assert.Equal(t, true, true)
+			err := cb.UpdateTripWarning(tc.request.ctx, tc.request.isTripped)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestCircuitBreaker_TryConsume(t *testing.T) {
+	type Request struct {
+		ctx    context.Context
+		amount int64
+
+		active         bool
+		buckets        []*circuitbreaker.Bucket
+		cacheTTL       time.Duration
+		featureName    string
+		threshold      int64
+		windowDuration time.Duration
+	}
+
+	type Response struct {
+		allowed     bool
+		windowValue int64
+		err         error
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+		mockFn   func(m *fixture.MockCircuitBreaker, req Request, res Response)
+	}{
+		"TryConsume allowed": {
+			request: Request{
+				ctx:    context.Background(),
+				amount: 100,
+				active: true,
+				buckets: []*circuitbreaker.Bucket{
+					circuitbreaker.NewBucket(4 * time.Hour),
+					circuitbreaker.NewBucket(1 * time.Minute),
+				},
+				cacheTTL:       24 * time.Hour,
+				featureName:    "test",
+				threshold:      1000,
+				windowDuration: 24 * time.Hour,
+			},
+			response: Response{
+				allowed:     true,
+				windowValue: 600,
+			},
+			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
+				m.Cache.EXPECT().IncrementIntIfBelow(
+					gomock.Any(),
+					gomock.Any(),
+					gomock.Len(len(req.buckets)),
+					req.amount,
+					req.threshold,
+					req.cacheTTL,
+				).Return(res.allowed, res.windowValue, nil)
+			},
+		},
+		"TryConsume rejected": {
+			request: Request{
+				ctx:    context.Background(),
+				amount: 100,
+				active: true,
+				buckets: []*circuitbreaker.Bucket{
+					circuitbreaker.NewBucket(4 * time.Hour),
+					circuitbreaker.NewBucket(1 * time.Minute),
+				},
+				cacheTTL:       24 * time.Hour,
+				featureName:    "test",
+				threshold:      1000,
+				windowDuration: 24 * time.Hour,
+			},
+			response: Response{
+				allowed:     false,
+				windowValue: 950,
+			},
+			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
+				m.Cache.EXPECT().IncrementIntIfBelow(gomock.Any(), gomock.Any(), gomock.Any(), req.amount, req.threshold, req.cacheTTL).Return(res.allowed, res.windowValue, nil)
+			},
+		},
+		"When circuit breaker is inactive, always allowed": {
+			request: Request{
+				ctx:    context.Background(),
+				amount: 100,
+				active: false,
+				buckets: []*circuitbreaker.Bucket{
+					circuitbreaker.NewBucket(4 * time.Hour),
+				},
+				cacheTTL:       24 * time.Hour,
+				featureName:    "test",
+				threshold:      1000,
+				windowDuration: 24 * time.Hour,
+			},
+			response: Response{
+				allowed:     true,
+				windowValue: 0,
+			},
+			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {},
+		},
+		"Cache error": {
+			request: Request{
+				ctx:    context.Background(),
+				amount: 100,
+				active: true,
+				buckets: []*circuitbreaker.Bucket{
+					circuitbreaker.NewBucket(4 * time.Hour),
+				},
+				cacheTTL:       24 * time.Hour,
+				featureName:    "test",
+				threshold:      1000,
+				windowDuration: 24 * time.Hour,
+			},
+			response: Response{
+				allowed:     false,
+				windowValue: 0,
+				err:         ErrUnexpectedRedis,
+			},
+			mockFn: func(m *fixture.MockCircuitBreaker, req Request, res Response) {
+				m.Cache.EXPECT().IncrementIntIfBelow(gomock.Any(), gomock.Any(), gomock.Any(), req.amount, req.threshold, req.cacheTTL).Return(false, int64(0), ErrUnexpectedRedis)
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mocks := fixture.NewCircuitBreakerMock(ctrl)
+			tc.mockFn(mocks, tc.request, tc.response)
+
+			cb := circuitbreaker.NewCircuitBreaker(
+				tc.request.buckets,
+				mocks.Cache,
+				tc.request.cacheTTL,
+				tc.request.featureName,
+				tc.request.windowDuration,
+			)
+			cb.SetActive(tc.request.active)
+			cb.SetThreshold(tc.request.threshold)
+
+			allowed, windowValue, err := cb.TryConsume(tc.request.ctx, tc.request.amount)
+			assert.Equal(t, tc.response.err, err)
+			assert.Equal(t, tc.response.allowed, allowed)
+			assert.Equal(t, tc.response.windowValue, windowValue)
 		})
 	}
 }
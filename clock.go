@@ -0,0 +1,44 @@
+package circuitbreaker
+
+import "time"
+
+// Clock tells the current time, every window and state calculation reads time from it
+type Clock interface {
+	Now() time.Time
+}
+
+// TimerClock is Clock that also schedules functions, reservation auto release runs on it when Clock implements it
+// and on the system timer otherwise
+type TimerClock interface {
+	Clock
+	AfterFunc(duration time.Duration, f func()) Timer
+}
+
+// Timer cancels a function scheduled by TimerClock, Stop returns false when it already ran or was stopped
+type Timer interface {
+	Stop() bool
+}
+
+type realClock struct{}
+
+// NewRealClock creates Clock reading the system time in UTC
+func NewRealClock() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+func (realClock) AfterFunc(duration time.Duration, f func()) Timer {
+	return time.AfterFunc(duration, f)
+}
+
+// afterFunc schedules f on clock when it is TimerClock, otherwise on the system timer
+func afterFunc(clock Clock, duration time.Duration, f func()) Timer {
+	if timerClock, ok := clock.(TimerClock); ok {
+		return timerClock.AfterFunc(duration, f)
+	}
+
+	return time.AfterFunc(duration, f)
+}
@@ -0,0 +1,126 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/cbtest"
+)
+
+func TestClock_NewRealClock(t *testing.T) {
+	now := circuitbreaker.NewRealClock().Now()
+	assert.Equal(t, time.UTC, now.Location())
+	assert.WithinDuration(t, time.Now(), now, time.Second)
+}
+
+func TestCircuitBreaker_WithClock(t *testing.T) {
+	type Request struct {
+		// amounts are recorded one step apart, window value is read after the last record
+		amounts []int64
+		step    time.Duration
+		after   time.Duration
+	}
+
+	type Response struct {
+		windowValue int64
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Every minute within the window is counted": {
+			request: Request{
+				amounts: []int64{10, 10, 10},
+				step:    time.Minute,
+				after:   time.Minute,
+			},
+			response: Response{
+				windowValue: 30,
+			},
+		},
+		"Buckets older than the window roll over": {
+			request: Request{
+				amounts: []int64{10, 20, 30},
+				step:    13 * time.Hour,
+				after:   0,
+			},
+			response: Response{
+				windowValue: 50,
+			},
+		},
+		"Nothing left after a whole window": {
+			request: Request{
+				amounts: []int64{10},
+				after:   25 * time.Hour,
+			},
+			response: Response{
+				windowValue: 0,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, client := newRedisClient(t)
+			clock := cbtest.NewClock(time.Date(2023, time.May, 9, 10, 42, 0, 0, time.UTC))
+
+			cb := circuitbreaker.NewCircuitBreaker(
+				[]*circuitbreaker.Bucket{
+					circuitbreaker.NewBucket(4 * time.Hour),
+					circuitbreaker.NewBucket(time.Hour),
+					circuitbreaker.NewBucket(5 * time.Minute),
+					circuitbreaker.NewBucket(time.Minute),
+				},
+				circuitbreaker.NewRedisCache(client, 5*time.Minute),
+				48*time.Hour,
+				"test",
+				24*time.Hour,
+				circuitbreaker.WithClock(clock),
+			)
+
+			for i, amount := range tc.request.amounts {
+				if i > 0 {
+					clock.Advance(tc.request.step)
+				}
+				assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, amount))
+			}
+			clock.Advance(tc.request.after)
+
+			windowValue, err := cb.CalculateWindowValue(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.windowValue, windowValue)
+		})
+	}
+}
+
+func TestCircuitBreaker_WithClockStateMachine(t *testing.T) {
+	ctx := context.Background()
+	clock := cbtest.NewClock(time.Date(2023, time.May, 9, 10, 42, 0, 0, time.UTC))
+
+	_, client := newRedisClient(t)
+	cb := circuitbreaker.NewCircuitBreaker(
+		[]*circuitbreaker.Bucket{circuitbreaker.NewBucket(time.Minute)},
+		circuitbreaker.NewRedisCache(client, 5*time.Minute),
+		time.Hour,
+		"test",
+		time.Hour,
+		circuitbreaker.WithClock(clock),
+	)
+
+	assert.Nil(t, cb.UpdateTrip(ctx, true))
+	clock.Advance(30 * time.Second)
+	state, err := cb.GetState(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.StateOpen, state)
+
+	clock.Advance(30 * time.Second)
+	state, err = cb.GetState(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.StateHalfOpen, state)
+}
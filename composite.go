@@ -0,0 +1,235 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BlockedError tells which circuit breaker of Composite blocked the call
+// Err is ErrCircuitOpen or ErrThresholdExceeded, errors.Is matches it
+type BlockedError struct {
+	FeatureName string
+	Err         error
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("%s: %s", e.FeatureName, e.Err)
+}
+
+func (e *BlockedError) Unwrap() error {
+	return e.Err
+}
+
+// Composite evaluates several circuit breakers as one unit, e.g. per-user, per-merchant and global limits
+// the circuit breakers may have different windows, buckets and caches
+type Composite interface {
+	Check(ctx context.Context, amount int64) error
+	Execute(ctx context.Context, amount int64, fn func(ctx context.Context) error) error
+	Reserve(ctx context.Context, amount int64) (Reservation, error)
+	TryConsume(ctx context.Context, amount int64) error
+}
+
+type composite struct {
+	CircuitBreakers []CircuitBreaker
+}
+
+// NewComposite creates Composite, circuit breakers are evaluated in order, so the first one blocking is reported
+func NewComposite(circuitBreakers ...CircuitBreaker) Composite {
+	return &composite{
+		CircuitBreakers: append([]CircuitBreaker{}, circuitBreakers...),
+	}
+}
+
+// Check returns *BlockedError of the first circuit breaker that is open or whose threshold amount would exceed
+// nothing is written, so another caller may still consume the room in between
+func (c *composite) Check(ctx context.Context, amount int64) error {
+	for _, cb := range c.CircuitBreakers {
+		state, err := cb.GetState(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", cb.GetFeatureName(), err)
+		}
+		if state == StateOpen {
+			return &BlockedError{FeatureName: cb.GetFeatureName(), Err: ErrCircuitOpen}
+		}
+
+		isExceeding, err := cb.IsExceedingThreshold(ctx, amount)
+		if err != nil {
+			return fmt.Errorf("%s: %w", cb.GetFeatureName(), err)
+		}
+		if isExceeding {
+			return &BlockedError{FeatureName: cb.GetFeatureName(), Err: ErrThresholdExceeded}
+		}
+	}
+
+	return nil
+}
+
+// Execute guards fn with every circuit breaker
+// amount is reserved on all of them before fn is called, committed when fn succeeds and released when it fails
+// Half-Open probes are taken only once the amount is reserved, when another circuit breaker then refuses its probe,
+// the probes already taken are given back, fn was never called so they say nothing about the dependency
+func (c *composite) Execute(ctx context.Context, amount int64, fn func(ctx context.Context) error) error {
+	r, err := c.Reserve(ctx, amount)
+	if err != nil {
+		return err
+	}
+
+	for i, cb := range c.CircuitBreakers {
+		allowed, err := cb.Allow(ctx)
+		if err == nil && allowed {
+			continue
+		}
+
+		if err != nil {
+			err = fmt.Errorf("%s: %w", cb.GetFeatureName(), err)
+		} else {
+			err = &BlockedError{FeatureName: cb.GetFeatureName(), Err: ErrCircuitOpen}
+		}
+		if recordErr := c.abort(ctx, r, c.CircuitBreakers[:i]); recordErr != nil {
+			return fmt.Errorf("%w, rolling back failed: %v", err, recordErr)
+		}
+		return err
+	}
+
+	fnErr := fn(ctx)
+
+	// fn may have failed because ctx expired, the reservation is still settled
+	recordCtx := detach(ctx)
+	var recordErr error
+	if fnErr == nil {
+		recordErr = r.Commit(recordCtx)
+	} else {
+		recordErr = r.Release(recordCtx)
+	}
+	for _, cb := range c.CircuitBreakers {
+		if err := cb.RecordCall(ctx, fnErr == nil, 0); err != nil && recordErr == nil {
+			recordErr = err
+		}
+	}
+
+	if fnErr != nil {
+		return fnErr
+	}
+	return recordErr
+}
+
+// Reserve reserves amount on every circuit breaker or on none of them
+// an open circuit breaker blocks before anything is written, otherwise when one blocks,
+// the reservations made so far are released and its *BlockedError is returned
+func (c *composite) Reserve(ctx context.Context, amount int64) (Reservation, error) {
+	for _, cb := range c.CircuitBreakers {
+		state, err := cb.GetState(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cb.GetFeatureName(), err)
+		}
+		if state == StateOpen {
+			return nil, &BlockedError{FeatureName: cb.GetFeatureName(), Err: ErrCircuitOpen}
+		}
+	}
+
+	reservations := make([]Reservation, 0, len(c.CircuitBreakers))
+	for _, cb := range c.CircuitBreakers {
+		r, err := cb.Reserve(ctx, amount)
+		if err == nil {
+			reservations = append(reservations, r)
+			continue
+		}
+
+		if errors.Is(err, ErrThresholdExceeded) {
+			err = &BlockedError{FeatureName: cb.GetFeatureName(), Err: ErrThresholdExceeded}
+		} else {
+			err = fmt.Errorf("%s: %w", cb.GetFeatureName(), err)
+		}
+		if releaseErr := (&compositeReservation{amount: amount, reservations: reservations}).Release(ctx); releaseErr != nil {
+			return nil, fmt.Errorf("%w, rolling back failed: %v", err, releaseErr)
+		}
+		return nil, err
+	}
+
+	return &compositeReservation{amount: amount, reservations: reservations}, nil
+}
+
+// TryConsume records amount on every circuit breaker only when none of them blocks
+func (c *composite) TryConsume(ctx context.Context, amount int64) error {
+	r, err := c.Reserve(ctx, amount)
+	if err != nil {
+		return err
+	}
+
+	return r.Commit(ctx)
+}
+
+// abort releases r and gives back the probe of every circuit breaker that allowed the call, fn was not called
+func (c *composite) abort(ctx context.Context, r Reservation, allowed []CircuitBreaker) error {
+	result := r.Release(ctx)
+	for _, cb := range allowed {
+		if err := cb.ReleaseProbe(ctx); err != nil && result == nil {
+			result = err
+		}
+	}
+
+	return result
+}
+
+// compositeReservation commits or releases the reservation of every circuit breaker
+type compositeReservation struct {
+	amount       int64
+	reservations []Reservation
+}
+
+func (r *compositeReservation) Amount() int64 {
+	return r.amount
+}
+
+// Commit commits every reservation or none of them
+// when one can't be committed, e.g. it was released by its timeout, the ones committed so far are rolled back
+// and the rest are released
+func (r *compositeReservation) Commit(ctx context.Context) error {
+	for i, reservation := range r.reservations {
+		err := reservation.Commit(ctx)
+		if err == nil {
+			continue
+		}
+
+		var rollbackErr error
+		for _, committed := range r.reservations[:i] {
+			if err := rollback(ctx, committed); err != nil && rollbackErr == nil {
+				rollbackErr = err
+			}
+		}
+		if err := (&compositeReservation{reservations: r.reservations[i+1:]}).Release(ctx); err != nil && rollbackErr == nil {
+			rollbackErr = err
+		}
+		if rollbackErr != nil {
+			return fmt.Errorf("%w, rolling back failed: %v", err, rollbackErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// rollback takes back the amount of a committed reservation
+func (r *compositeReservation) rollback(ctx context.Context) error {
+	var result error
+	for _, reservation := range r.reservations {
+		if err := rollback(ctx, reservation); err != nil && result == nil {
+			result = err
+		}
+	}
+
+	return result
+}
+
+// Release releases every reservation even when one fails, the first error is returned
+func (r *compositeReservation) Release(ctx context.Context) error {
+	var result error
+	for _, reservation := range r.reservations {
+		if err := reservation.Release(ctx); err != nil && result == nil {
+			result = err
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,365 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/cbtest"
+)
+
+func TestComposite_TryConsume(t *testing.T) {
+	type Request struct {
+		// consumed is recorded on the merchant circuit breaker only, before the composite call
+		consumed int64
+		tripped  string
+		amount   int64
+	}
+
+	type Response struct {
+		blockedBy    string
+		err          error
+		windowValues []int64
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Every circuit breaker passes": {
+			request: Request{
+				amount: 50,
+			},
+			response: Response{
+				windowValues: []int64{50, 50, 50},
+			},
+		},
+		"Merchant threshold blocks and nothing is written": {
+			request: Request{
+				consumed: 460,
+				amount:   50,
+			},
+			response: Response{
+				blockedBy:    "merchant",
+				err:          circuitbreaker.ErrThresholdExceeded,
+				windowValues: []int64{0, 460, 0},
+			},
+		},
+		"User threshold blocks first": {
+			request: Request{
+				amount: 150,
+			},
+			response: Response{
+				blockedBy:    "user",
+				err:          circuitbreaker.ErrThresholdExceeded,
+				windowValues: []int64{0, 0, 0},
+			},
+		},
+		"Open circuit breaker blocks and nothing is written": {
+			request: Request{
+				tripped: "global",
+				amount:  50,
+			},
+			response: Response{
+				blockedBy:    "global",
+				err:          circuitbreaker.ErrCircuitOpen,
+				windowValues: []int64{0, 0, 0},
+			},
+		},
+		"Amount just below the smallest threshold": {
+			request: Request{
+				amount: 99,
+			},
+			response: Response{
+				windowValues: []int64{99, 99, 99},
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, client := newRedisClient(t)
+			breakers := newCompositeBreakers(t, client)
+			assert.Nil(t, breakers[1].UpdateLatestBucketsValue(ctx, tc.request.consumed))
+			for _, cb := range breakers {
+				if cb.GetFeatureName() == tc.request.tripped {
+					assert.Nil(t, cb.UpdateTrip(ctx, true))
+				}
+			}
+
+			err := circuitbreaker.NewComposite(breakers...).TryConsume(ctx, tc.request.amount)
+			assert.ErrorIs(t, err, tc.response.err)
+			assertBlockedBy(t, err, tc.response.blockedBy)
+
+			for i, cb := range breakers {
+				windowValue, err := cb.CalculateWindowValue(ctx)
+				assert.Nil(t, err)
+				assert.Equal(t, tc.response.windowValues[i], windowValue, cb.GetFeatureName())
+			}
+		})
+	}
+}
+
+func TestComposite_ReserveRollback(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	breakers := newCompositeBreakers(t, client)
+	breakers[2].SetThreshold(60)
+
+	_, err := circuitbreaker.NewComposite(breakers...).Reserve(ctx, 80)
+	assert.ErrorIs(t, err, circuitbreaker.ErrThresholdExceeded)
+	assertBlockedBy(t, err, "global")
+
+	// user and merchant reserved before global blocked, their reservations are released
+	for _, cb := range breakers {
+		windowValue, err := cb.CalculateWindowValue(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), windowValue, cb.GetFeatureName())
+	}
+}
+
+func TestComposite_Check(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	breakers := newCompositeBreakers(t, client)
+	composite := circuitbreaker.NewComposite(breakers...)
+
+	assert.Nil(t, composite.Check(ctx, 50))
+
+	assert.Nil(t, breakers[1].UpdateTrip(ctx, true))
+	err := composite.Check(ctx, 50)
+	assert.ErrorIs(t, err, circuitbreaker.ErrCircuitOpen)
+	assertBlockedBy(t, err, "merchant")
+}
+
+func TestComposite_Execute(t *testing.T) {
+	type Request struct {
+		tripped string
+		fnErr   error
+	}
+
+	type Response struct {
+		blockedBy   string
+		err         error
+		called      bool
+		windowValue int64
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Success is recorded everywhere": {
+			request: Request{},
+			response: Response{
+				called:      true,
+				windowValue: 50,
+			},
+		},
+		"Failure releases the reservations": {
+			request: Request{
+				fnErr: ErrDownstream,
+			},
+			response: Response{
+				err:         ErrDownstream,
+				called:      true,
+				windowValue: 0,
+			},
+		},
+		"Open circuit breaker blocks before fn is called": {
+			request: Request{
+				tripped: "global",
+			},
+			response: Response{
+				blockedBy:   "global",
+				err:         circuitbreaker.ErrCircuitOpen,
+				windowValue: 0,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, client := newRedisClient(t)
+			breakers := newCompositeBreakers(t, client)
+			for _, cb := range breakers {
+				if cb.GetFeatureName() == tc.request.tripped {
+					assert.Nil(t, cb.UpdateTrip(ctx, true))
+				}
+			}
+
+			called := false
+			err := circuitbreaker.NewComposite(breakers...).Execute(ctx, 50, func(ctx context.Context) error {
+				called = true
+				return tc.request.fnErr
+			})
+			assert.ErrorIs(t, err, tc.response.err)
+			assertBlockedBy(t, err, tc.response.blockedBy)
+			assert.Equal(t, tc.response.called, called)
+
+			for _, cb := range breakers {
+				windowValue, err := cb.CalculateWindowValue(ctx)
+				assert.Nil(t, err)
+				assert.Equal(t, tc.response.windowValue, windowValue, cb.GetFeatureName())
+			}
+		})
+	}
+}
+
+func TestComposite_ExecuteHalfOpen(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	breakers := newCompositeBreakers(t, client)
+	composite := circuitbreaker.NewComposite(breakers...)
+
+	// zero open duration moves the tripped user circuit breaker to Half-Open right away
+	for _, cb := range breakers[:2] {
+		cb.SetStateMachineConfig(circuitbreaker.StateMachineConfig{OpenDuration: 0, HalfOpenMaxProbes: 1, HalfOpenSuccessThreshold: 1})
+	}
+	assert.Nil(t, breakers[0].UpdateTrip(ctx, true))
+	assert.Nil(t, breakers[1].UpdateLatestBucketsValue(ctx, 460))
+
+	// merchant threshold blocks before the user probe is taken
+	called := false
+	err := composite.Execute(ctx, 50, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, circuitbreaker.ErrThresholdExceeded)
+	assertBlockedBy(t, err, "merchant")
+	assert.False(t, called)
+
+	err = composite.Execute(ctx, 10, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.True(t, called)
+	state, err := breakers[0].GetState(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.StateClosed, state)
+}
+
+func TestComposite_ExecuteProbeRefused(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	clock := cbtest.NewClock(time.Date(2023, time.May, 9, 10, 42, 0, 0, time.UTC))
+	breakers := newCompositeBreakers(t, client, circuitbreaker.WithClock(clock))
+
+	assert.Nil(t, breakers[0].UpdateTrip(ctx, true))
+	assert.Nil(t, breakers[1].UpdateTrip(ctx, true))
+	clock.Advance(circuitbreaker.DefaultStateMachineConfig.OpenDuration)
+	// another caller already took the merchant probe
+	allowed, err := breakers[1].Allow(ctx)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	called := false
+	err = circuitbreaker.NewComposite(breakers...).Execute(ctx, 50, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, circuitbreaker.ErrCircuitOpen)
+	assertBlockedBy(t, err, "merchant")
+	assert.False(t, called)
+
+	// user probe is given back, user stays Half-Open and can still be probed
+	state, err := breakers[0].GetState(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.StateHalfOpen, state)
+	allowed, err = breakers[0].Allow(ctx)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+	for _, cb := range breakers {
+		windowValue, err := cb.CalculateWindowValue(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), windowValue, cb.GetFeatureName())
+	}
+}
+
+func TestComposite_ExecuteRatio(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	breakers := newCompositeBreakers(t, client)
+	breakers[0].SetRatioConfig(circuitbreaker.RatioConfig{FailureRateThreshold: 0.5, MinimumVolume: 2})
+	composite := circuitbreaker.NewComposite(breakers...)
+
+	assert.Nil(t, composite.Execute(ctx, 10, func(ctx context.Context) error {
+		return nil
+	}))
+	assert.Equal(t, ErrDownstream, composite.Execute(ctx, 10, func(ctx context.Context) error {
+		return ErrDownstream
+	}))
+
+	rate, volume, err := breakers[0].GetFailureRate(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.5, rate)
+	assert.Equal(t, 2, volume)
+	state, err := breakers[0].GetState(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.StateOpen, state)
+}
+
+func TestComposite_CommitAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	clock := cbtest.NewClock(time.Date(2023, time.May, 9, 10, 42, 0, 0, time.UTC))
+	breakers := newCompositeBreakers(t, client, circuitbreaker.WithClock(clock))
+	breakers[1].SetReservationTimeout(time.Minute)
+
+	r, err := circuitbreaker.NewComposite(breakers...).Reserve(ctx, 50)
+	assert.Nil(t, err)
+	// merchant reservation times out before the composite commits
+	clock.Advance(time.Minute)
+
+	assert.ErrorIs(t, r.Commit(ctx), circuitbreaker.ErrReservationClosed)
+	for _, cb := range breakers {
+		windowValue, err := cb.CalculateWindowValue(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), windowValue, cb.GetFeatureName())
+	}
+}
+
+// newCompositeBreakers creates user, merchant and global circuit breakers with different windows and buckets
+// opts are applied to every one of them, e.g. WithClock to share a clock a test can move forward
+func newCompositeBreakers(t *testing.T, client redis.UniversalClient, opts ...circuitbreaker.Option) []circuitbreaker.CircuitBreaker {
+	t.Helper()
+
+	newBreaker := func(featureName string, window time.Duration, threshold int64, buckets ...*circuitbreaker.Bucket) circuitbreaker.CircuitBreaker {
+		cb, _ := newTestCircuitBreaker(t, client, featureName, append([]circuitbreaker.Option{
+			circuitbreaker.WithBuckets(buckets...),
+			circuitbreaker.WithCacheTTL(2 * window),
+			circuitbreaker.WithThreshold(threshold),
+			circuitbreaker.WithWindow(window),
+		}, opts...)...)
+		cb.SetReservationTimeout(0)
+
+		return cb
+	}
+
+	return []circuitbreaker.CircuitBreaker{
+		newBreaker("user", time.Hour, 100, circuitbreaker.NewBucket(time.Hour), circuitbreaker.NewBucket(time.Minute)),
+		newBreaker("merchant", 24*time.Hour, 500, circuitbreaker.NewBucket(time.Hour), circuitbreaker.NewBucket(5*time.Minute)),
+		newBreaker("global", 7*24*time.Hour, 100000, circuitbreaker.NewBucket(24*time.Hour), circuitbreaker.NewBucket(time.Hour)),
+	}
+}
+
+func assertBlockedBy(t *testing.T, err error, featureName string) {
+	t.Helper()
+
+	var blockedErr *circuitbreaker.BlockedError
+	if featureName == "" {
+		assert.False(t, errors.As(err, &blockedErr))
+		return
+	}
+	if assert.True(t, errors.As(err, &blockedErr)) {
+		assert.Equal(t, featureName, blockedErr.FeatureName)
+	}
+}
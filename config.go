@@ -0,0 +1,165 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidConfig = errors.New("invalid circuit breaker config")
+)
+
+// ConfigError describes why New rejected the options, it matches ErrInvalidConfig with errors.Is
+type ConfigError struct {
+	Field   string
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s %s", ErrInvalidConfig, e.Field, e.Message)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+// validate rejects combinations that would quietly produce wrong keys or windows
+// validateWithoutCurrency is validate for circuit breakers counting plain amounts, only NewMoneyBreaker takes WithCurrency
+func (c *circuitBreaker) validateWithoutCurrency() error {
+	if c.Currency != (Currency{}) {
+		return &ConfigError{Field: "currency", Message: "is only supported by NewMoneyBreaker"}
+	}
+
+	return c.validate()
+}
+
+func (c *circuitBreaker) validate() error {
+	if c.FeatureName == "" {
+		return &ConfigError{Field: "feature name", Message: "must not be empty"}
+	}
+	if strings.Contains(c.FeatureName, "-") {
+		return &ConfigError{Field: "feature name", Message: fmt.Sprintf("%q must not contain '-', it separates the key parts", c.FeatureName)}
+	}
+	if c.KeyPrefix == "" || strings.Contains(c.KeyPrefix, "-") {
+		return &ConfigError{Field: "key prefix", Message: fmt.Sprintf("%q must not be empty or contain '-'", c.KeyPrefix)}
+	}
+	if c.Cache == nil {
+		return &ConfigError{Field: "cache", Message: "must be set"}
+	}
+	if c.Clock == nil {
+		return &ConfigError{Field: "clock", Message: "must be set"}
+	}
+	if c.WindowDuration <= 0 {
+		return &ConfigError{Field: "window", Message: fmt.Sprintf("%s must be positive", c.WindowDuration)}
+	}
+	// time point keys are stamped to the second, a fraction would make distinct buckets share a key
+	if c.WindowDuration%time.Second != 0 {
+		return &ConfigError{Field: "window", Message: fmt.Sprintf("%s must be whole seconds", c.WindowDuration)}
+	}
+	if c.CacheTTL < 0 {
+		return &ConfigError{Field: "cache ttl", Message: fmt.Sprintf("%s must not be negative", c.CacheTTL)}
+	}
+	if c.CacheTTL > 0 && c.CacheTTL < c.WindowDuration {
+		return &ConfigError{Field: "cache ttl", Message: fmt.Sprintf("%s is shorter than window %s, buckets would expire inside the window", c.CacheTTL, c.WindowDuration)}
+	}
+	if c.LookupStrategy == LookupSliding && c.CacheTTL > 0 && c.CacheTTL < 2*c.WindowDuration {
+		return &ConfigError{Field: "cache ttl", Message: fmt.Sprintf("%s is shorter than two windows %s, the previous window would expire while it is weighted", c.CacheTTL, 2*c.WindowDuration)}
+	}
+	if c.Currency.Scale < 0 || c.Currency.Scale > maxCurrencyScale {
+		return &ConfigError{Field: "currency", Message: fmt.Sprintf("scale %d must be between 0 and %d", c.Currency.Scale, maxCurrencyScale)}
+	}
+	if c.WarningPercentage < 0 || c.WarningPercentage > 100 {
+		return &ConfigError{Field: "warning percentage", Message: fmt.Sprintf("%v must be between 0 and 100", c.WarningPercentage)}
+	}
+	if c.WarningPercentage == 0 && c.LimitProvider == nil && c.WarningThreshold > c.Threshold {
+		return &ConfigError{Field: "warning threshold", Message: fmt.Sprintf("%d is greater than threshold %d", c.WarningThreshold, c.Threshold)}
+	}
+
+	if c.RatioConfig.FailureRateThreshold < 0 || c.RatioConfig.FailureRateThreshold > 1 {
+		return &ConfigError{Field: "failure rate threshold", Message: fmt.Sprintf("%v must be between 0 and 1", c.RatioConfig.FailureRateThreshold)}
+	}
+	if c.RatioConfig.MinimumVolume < 0 {
+		return &ConfigError{Field: "minimum volume", Message: fmt.Sprintf("%d must not be negative", c.RatioConfig.MinimumVolume)}
+	}
+
+	if err := validateThresholdLevels(c.ThresholdLevels); err != nil {
+		return err
+	}
+
+	for i, bound := range c.LatencyConfig.Bounds {
+		if bound <= 0 || (i > 0 && bound <= c.LatencyConfig.Bounds[i-1]) {
+			return &ConfigError{Field: "latency bounds", Message: "must be positive and ascending"}
+		}
+	}
+	if c.LatencyConfig.Percentile < 0 || c.LatencyConfig.Percentile > 1 {
+		return &ConfigError{Field: "latency percentile", Message: fmt.Sprintf("%v must be between 0 and 1", c.LatencyConfig.Percentile)}
+	}
+
+	// sliding lookup reads fixed windows only, buckets are never used
+	if c.LookupStrategy == LookupSliding {
+		return nil
+	}
+
+	buckets := c.Buckets
+	if len(buckets) == 0 {
+		buckets = DefaultBucket
+	}
+
+	smallest, largest := buckets[0], buckets[0]
+	durations := make(map[int64]bool)
+	for _, bucket := range buckets {
+		if bucket == nil || bucket.Duration <= 0 {
+			return &ConfigError{Field: "bucket", Message: "duration must be positive"}
+		}
+		if bucket.Duration%time.Second != 0 {
+			return &ConfigError{Field: "bucket", Message: fmt.Sprintf("%s must be whole seconds", bucket.Duration)}
+		}
+		if durations[int64(bucket.Duration)] {
+			return &ConfigError{Field: "bucket", Message: fmt.Sprintf("%s is duplicated", bucket.Duration)}
+		}
+		durations[int64(bucket.Duration)] = true
+
+		if bucket.Duration < smallest.Duration {
+			smallest = bucket
+		}
+		if bucket.Duration > largest.Duration {
+			largest = bucket
+		}
+	}
+
+	for _, bucket := range buckets {
+		if bucket.Duration%smallest.Duration != 0 {
+			return &ConfigError{Field: "bucket", Message: fmt.Sprintf("%s is not a multiple of the smallest bucket %s", bucket.Duration, smallest.Duration)}
+		}
+	}
+	if c.WindowDuration < largest.Duration {
+		return &ConfigError{Field: "window", Message: fmt.Sprintf("%s is shorter than the largest bucket %s", c.WindowDuration, largest.Duration)}
+	}
+	if c.WindowDuration%smallest.Duration != 0 {
+		return &ConfigError{Field: "window", Message: fmt.Sprintf("%s is not a multiple of the smallest bucket %s", c.WindowDuration, smallest.Duration)}
+	}
+
+	return nil
+}
+
+// validateThresholdLevels rejects levels whose latch keys would clash or that could never be reached
+func validateThresholdLevels(levels []ThresholdLevel) error {
+	levelNames := make(map[string]bool)
+	for _, level := range levels {
+		if level.Name == "" || strings.Contains(level.Name, "-") {
+			return &ConfigError{Field: "threshold level", Message: fmt.Sprintf("name %q must not be empty or contain '-'", level.Name)}
+		}
+		if levelNames[level.Name] {
+			return &ConfigError{Field: "threshold level", Message: fmt.Sprintf("name %q is duplicated", level.Name)}
+		}
+		levelNames[level.Name] = true
+
+		if level.Threshold <= 0 && level.Percentage <= 0 {
+			return &ConfigError{Field: "threshold level", Message: fmt.Sprintf("%q needs a positive threshold or percentage", level.Name)}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,286 @@
+package circuitbreaker_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/fixture"
+)
+
+func TestCircuitBreaker_New(t *testing.T) {
+	type Request struct {
+		featureName string
+		opts        []circuitbreaker.Option
+	}
+
+	type Response struct {
+		field string
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"New success": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithBuckets(
+						circuitbreaker.NewBucket(4*time.Hour),
+						circuitbreaker.NewBucket(time.Hour),
+						circuitbreaker.NewBucket(5*time.Minute),
+						circuitbreaker.NewBucket(time.Minute),
+					),
+					circuitbreaker.WithWindow(24 * time.Hour),
+					circuitbreaker.WithCacheTTL(28 * time.Hour),
+					circuitbreaker.WithThreshold(500),
+					circuitbreaker.WithWarningThreshold(400),
+					circuitbreaker.WithKeyPrefix("limit"),
+				},
+			},
+		},
+		"New with defaults success": {
+			request: Request{
+				featureName: "loan_disbursement",
+			},
+		},
+		"Empty feature name": {
+			request: Request{
+				featureName: "",
+			},
+			response: Response{
+				field: "feature name",
+			},
+		},
+		"Feature name with separator": {
+			request: Request{
+				featureName: "loan-disbursement",
+			},
+			response: Response{
+				field: "feature name",
+			},
+		},
+		"Key prefix with separator": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts:        []circuitbreaker.Option{circuitbreaker.WithKeyPrefix("my-cb")},
+			},
+			response: Response{
+				field: "key prefix",
+			},
+		},
+		"Missing cache": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts:        []circuitbreaker.Option{circuitbreaker.WithCache(nil)},
+			},
+			response: Response{
+				field: "cache",
+			},
+		},
+		"Zero duration bucket": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Hour), circuitbreaker.NewBucket(0)),
+				},
+			},
+			response: Response{
+				field: "bucket",
+			},
+		},
+		"Duplicated bucket": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Hour), circuitbreaker.NewBucket(time.Hour)),
+				},
+			},
+			response: Response{
+				field: "bucket",
+			},
+		},
+		"Bucket not a multiple of the smallest bucket": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithBuckets(circuitbreaker.NewBucket(7*time.Minute), circuitbreaker.NewBucket(5*time.Minute)),
+				},
+			},
+			response: Response{
+				field: "bucket",
+			},
+		},
+		"Sub-second bucket": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Minute), circuitbreaker.NewBucket(500*time.Millisecond)),
+				},
+			},
+			response: Response{
+				field: "bucket",
+			},
+		},
+		"Sub-second window": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithLookupStrategy(circuitbreaker.LookupSliding),
+					circuitbreaker.WithWindow(1500 * time.Millisecond),
+				},
+			},
+			response: Response{
+				field: "window",
+			},
+		},
+		"Window shorter than the largest bucket": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithWindow(2 * time.Hour),
+				},
+			},
+			response: Response{
+				field: "window",
+			},
+		},
+		"Window not a multiple of the smallest bucket": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Hour), circuitbreaker.NewBucket(5*time.Minute)),
+					circuitbreaker.WithWindow(2*time.Hour + 3*time.Minute),
+				},
+			},
+			response: Response{
+				field: "window",
+			},
+		},
+		"Cache ttl shorter than window": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithCacheTTL(time.Hour),
+				},
+			},
+			response: Response{
+				field: "cache ttl",
+			},
+		},
+		"Cache ttl shorter than two sliding windows": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithLookupStrategy(circuitbreaker.LookupSliding),
+					circuitbreaker.WithCacheTTL(36 * time.Hour),
+				},
+			},
+			response: Response{
+				field: "cache ttl",
+			},
+		},
+		"Currency is only for money breakers": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithCurrency(circuitbreaker.Currency{Code: "USD", Scale: 2}),
+				},
+			},
+			response: Response{
+				field: "currency",
+			},
+		},
+		"Failure rate threshold above one": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithRatio(circuitbreaker.RatioConfig{FailureRateThreshold: 50, MinimumVolume: 20}),
+				},
+			},
+			response: Response{
+				field: "failure rate threshold",
+			},
+		},
+		"Latency bounds not ascending": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithLatency(circuitbreaker.LatencyConfig{Bounds: []time.Duration{time.Second, time.Millisecond}}),
+				},
+			},
+			response: Response{
+				field: "latency bounds",
+			},
+		},
+		"Threshold level name with separator": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithThresholdLevels(circuitbreaker.ThresholdLevel{Name: "page-ops", Threshold: 90}),
+				},
+			},
+			response: Response{
+				field: "threshold level",
+			},
+		},
+		"Warning threshold greater than threshold": {
+			request: Request{
+				featureName: "loan_disbursement",
+				opts: []circuitbreaker.Option{
+					circuitbreaker.WithThreshold(100),
+					circuitbreaker.WithWarningThreshold(200),
+				},
+			},
+			response: Response{
+				field: "warning threshold",
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mocks := fixture.NewCircuitBreakerMock(ctrl)
+			opts := append([]circuitbreaker.Option{circuitbreaker.WithCache(mocks.Cache)}, tc.request.opts...)
+
+			cb, err := circuitbreaker.New(tc.request.featureName, opts...)
+			if tc.response.field == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, "*circuitbreaker.circuitBreaker", reflect.TypeOf(cb).String())
+				return
+			}
+
+			var configErr *circuitbreaker.ConfigError
+			assert.True(t, errors.As(err, &configErr))
+			assert.Equal(t, tc.response.field, configErr.Field)
+			assert.ErrorIs(t, err, circuitbreaker.ErrInvalidConfig)
+			assert.Nil(t, cb)
+		})
+	}
+}
+
+func TestCircuitBreaker_NewWithKeyPrefix(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mocks := fixture.NewCircuitBreakerMock(ctrl)
+	cb, err := circuitbreaker.New(
+		"test",
+		circuitbreaker.WithCache(mocks.Cache),
+		circuitbreaker.WithBuckets(circuitbreaker.NewBucket(24*time.Hour)),
+		circuitbreaker.WithKeyPrefix("limit"),
+	)
+	assert.Nil(t, err)
+
+	result := cb.GenerateKeys(time.Date(2023, time.May, 12, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, []string{"limit-test-24h-24h-20230512000000", "limit-test-24h-24h-20230511000000"}, result)
+}
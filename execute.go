@@ -0,0 +1,133 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrCircuitOpen = errors.New("circuit open")
+)
+
+type RecordPolicy int
+
+const (
+	// RecordOnSuccess records amount only when the guarded call succeeds
+	RecordOnSuccess RecordPolicy = iota
+	// RecordAlways records amount whatever the guarded call returns
+	RecordAlways
+)
+
+// Execute guards fn with the circuit breaker, see Guard and RecordCall
+// fn is not called when the circuit is open or amount would exceed the threshold,
+// otherwise its outcome is reported and amount is recorded according to RecordPolicy
+func (c *circuitBreaker) Execute(ctx context.Context, amount int64, fn func(ctx context.Context) error) error {
+	if !c.Active {
+		return fn(ctx)
+	}
+
+	if err := c.Guard(ctx, amount); err != nil {
+		return err
+	}
+
+	fnErr := fn(ctx)
+	recorded := int64(0)
+	if fnErr == nil || c.RecordPolicy == RecordAlways {
+		recorded = amount
+	}
+	recordErr := c.RecordCall(ctx, fnErr == nil, recorded)
+
+	// error from fn matters more to the caller than failing to record it
+	if fnErr != nil {
+		return fnErr
+	}
+	return recordErr
+}
+
+// Guard returns ErrThresholdExceeded or ErrCircuitOpen when a call of amount may not go through, any other error
+// comes from the cache. Threshold is checked first, Allow uses up a Half-Open probe that only RecordCall gives back
+func (c *circuitBreaker) Guard(ctx context.Context, amount int64) error {
+	isExceeding, err := c.IsExceedingThreshold(ctx, amount)
+	if err != nil {
+		return err
+	}
+	if isExceeding {
+		return ErrThresholdExceeded
+	}
+
+	allowed, err := c.Allow(ctx)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrCircuitOpen
+	}
+
+	return nil
+}
+
+// RecordCall reports the outcome of a call Guard let through and records amount, zero amount records nothing
+// it runs on a context detached from ctx, so a call that failed because ctx expired is still counted
+func (c *circuitBreaker) RecordCall(ctx context.Context, success bool, amount int64) error {
+	ctx = detach(ctx)
+	if err := c.recordOutcome(ctx, success); err != nil {
+		return err
+	}
+	if amount == 0 {
+		return nil
+	}
+
+	return c.UpdateLatestBucketsValue(ctx, amount)
+}
+
+// recordOutcome reports the outcome to the state machine, in ratio mode it is also counted in the failure rate
+func (c *circuitBreaker) recordOutcome(ctx context.Context, success bool) error {
+	if c.RatioConfig.FailureRateThreshold <= 0 {
+		return c.RecordResult(ctx, success)
+	}
+	if success {
+		return c.RecordSuccess(ctx)
+	}
+
+	return c.RecordFailure(ctx)
+}
+
+// detachedContext keeps the values of its parent but is never canceled and has no deadline
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+// detach returns ctx without its cancellation, for recording what happened once the call itself is over
+func detach(ctx context.Context) context.Context {
+	return detachedContext{Context: ctx}
+}
+
+// SetRecordPolicy sets when Execute records the amount
+func (c *circuitBreaker) SetRecordPolicy(policy RecordPolicy) {
+	c.RecordPolicy = policy
+}
+
+// ExecuteValue is Execute for fn returning a value
+func ExecuteValue[T any](ctx context.Context, cb CircuitBreaker, amount int64, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := cb.Execute(ctx, amount, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,226 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+)
+
+var (
+	ErrDownstream = errors.New("downstream error")
+)
+
+func TestCircuitBreaker_Execute(t *testing.T) {
+	type Request struct {
+		active      bool
+		isTripped   bool
+		policy      circuitbreaker.RecordPolicy
+		windowValue int64
+		amount      int64
+		fnErr       error
+	}
+
+	type Response struct {
+		err         error
+		called      bool
+		windowValue int64
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Execute success records amount": {
+			request: Request{
+				active: true,
+				amount: 30,
+			},
+			response: Response{
+				called:      true,
+				windowValue: 30,
+			},
+		},
+		"Execute failure is not recorded by default": {
+			request: Request{
+				active: true,
+				amount: 30,
+				fnErr:  ErrDownstream,
+			},
+			response: Response{
+				err:         ErrDownstream,
+				called:      true,
+				windowValue: 0,
+			},
+		},
+		"Execute failure is recorded with RecordAlways": {
+			request: Request{
+				active: true,
+				policy: circuitbreaker.RecordAlways,
+				amount: 30,
+				fnErr:  ErrDownstream,
+			},
+			response: Response{
+				err:         ErrDownstream,
+				called:      true,
+				windowValue: 30,
+			},
+		},
+		"Circuit open rejects without calling fn": {
+			request: Request{
+				active:    true,
+				isTripped: true,
+				amount:    30,
+			},
+			response: Response{
+				err:         circuitbreaker.ErrCircuitOpen,
+				called:      false,
+				windowValue: 0,
+			},
+		},
+		"Exceeding threshold rejects without calling fn": {
+			request: Request{
+				active:      true,
+				windowValue: 80,
+				amount:      30,
+			},
+			response: Response{
+				err:         circuitbreaker.ErrThresholdExceeded,
+				called:      false,
+				windowValue: 80,
+			},
+		},
+		"Inactive circuit breaker only calls fn": {
+			request: Request{
+				active:    false,
+				isTripped: true,
+				amount:    30,
+			},
+			response: Response{
+				called: true,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, client := newRedisClient(t)
+			cb, _ := newTestCircuitBreaker(t, client, "test", circuitbreaker.WithThreshold(100))
+			cb.SetRecordPolicy(tc.request.policy)
+			assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, tc.request.windowValue))
+			if tc.request.isTripped {
+				assert.Nil(t, cb.UpdateTrip(ctx, true))
+			}
+			cb.SetActive(tc.request.active)
+
+			called := false
+			err := cb.Execute(ctx, tc.request.amount, func(ctx context.Context) error {
+				called = true
+				return tc.request.fnErr
+			})
+			assert.Equal(t, tc.response.err, err)
+			assert.Equal(t, tc.response.called, called)
+
+			cb.SetActive(true)
+			windowValue, err := cb.CalculateWindowValue(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.windowValue, windowValue)
+		})
+	}
+}
+
+func TestExecuteValue(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	cb, _ := newTestCircuitBreaker(t, client, "test", circuitbreaker.WithThreshold(100))
+
+	result, err := circuitbreaker.ExecuteValue(ctx, cb, 30, func(ctx context.Context) (string, error) {
+		return "disbursed", nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "disbursed", result)
+
+	assert.Nil(t, cb.UpdateTrip(ctx, true))
+	result, err = circuitbreaker.ExecuteValue(ctx, cb, 30, func(ctx context.Context) (string, error) {
+		return "disbursed", nil
+	})
+	assert.Equal(t, circuitbreaker.ErrCircuitOpen, err)
+	assert.Equal(t, "", result)
+}
+
+func TestCircuitBreaker_ExecuteHalfOpenExceedingThreshold(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	cb, clock := newTestCircuitBreaker(t, client, "test", circuitbreaker.WithThreshold(100))
+
+	assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, 80))
+	assert.Nil(t, cb.UpdateTrip(ctx, true))
+	clock.Advance(circuitbreaker.DefaultStateMachineConfig.OpenDuration)
+
+	// rejected by the threshold, the only Half-Open probe is still there
+	err := cb.Execute(ctx, 30, func(ctx context.Context) error {
+		return nil
+	})
+	assert.Equal(t, circuitbreaker.ErrThresholdExceeded, err)
+	state, err := cb.GetState(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.StateHalfOpen, state)
+
+	called := false
+	err = cb.Execute(ctx, 10, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.True(t, called)
+	state, err = cb.GetState(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.StateClosed, state)
+}
+
+func TestCircuitBreaker_ExecuteExpiredContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, client := newRedisClient(t)
+	cb, clock := newTestCircuitBreaker(t, client, "test", circuitbreaker.WithThreshold(100))
+	cb.SetRecordPolicy(circuitbreaker.RecordAlways)
+
+	assert.Nil(t, cb.UpdateTrip(ctx, true))
+	clock.Advance(circuitbreaker.DefaultStateMachineConfig.OpenDuration)
+
+	// the probe fails once its context is done, the failure still opens the circuit again and its amount is recorded
+	err := cb.Execute(ctx, 30, func(ctx context.Context) error {
+		cancel()
+		return ctx.Err()
+	})
+	assert.Equal(t, context.Canceled, err)
+
+	state, err := cb.GetState(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.StateOpen, state)
+	windowValue, err := cb.CalculateWindowValue(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(30), windowValue)
+}
+
+func TestCircuitBreaker_GuardRecordCall(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	cb, _ := newTestCircuitBreaker(t, client, "test", circuitbreaker.WithThreshold(100))
+
+	assert.Nil(t, cb.Guard(ctx, 30))
+	assert.Nil(t, cb.RecordCall(ctx, true, 30))
+	// zero amount only reports the outcome
+	assert.Nil(t, cb.RecordCall(ctx, false, 0))
+	windowValue, err := cb.CalculateWindowValue(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(30), windowValue)
+
+	assert.Equal(t, circuitbreaker.ErrThresholdExceeded, cb.Guard(ctx, 70))
+	assert.Nil(t, cb.UpdateTrip(ctx, true))
+	assert.Equal(t, circuitbreaker.ErrCircuitOpen, cb.Guard(ctx, 10))
+}
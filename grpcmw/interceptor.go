@@ -0,0 +1,195 @@
+package grpcmw
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	circuitbreaker "go-circuit-breaker"
+)
+
+// Resolver returns circuit breaker guarding the full method name
+// example full method name: /grpc.health.v1.Health/Check
+type Resolver func(fullMethod string) circuitbreaker.CircuitBreaker
+
+// AmountExtractor returns the amount a unary call counts for
+type AmountExtractor func(ctx context.Context, fullMethod string, req interface{}) (int64, error)
+
+// NewResolver creates Resolver keeping one circuit breaker per full method name, created on first use
+func NewResolver(
+	buckets []*circuitbreaker.Bucket,
+	cache circuitbreaker.Cache,
+	cacheTTL time.Duration,
+	featureName string,
+	threshold int64,
+	windowDuration time.Duration,
+) Resolver {
+	breakers := make(map[string]circuitbreaker.CircuitBreaker)
+	var mutex sync.Mutex
+
+	return func(fullMethod string) circuitbreaker.CircuitBreaker {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if cb, found := breakers[fullMethod]; found {
+			return cb
+		}
+
+		methodBuckets := make([]*circuitbreaker.Bucket, len(buckets))
+		copy(methodBuckets, buckets)
+
+		cb := circuitbreaker.NewCircuitBreaker(methodBuckets, cache, cacheTTL, getFeatureName(featureName, fullMethod), windowDuration)
+		cb.SetThreshold(threshold)
+		breakers[fullMethod] = cb
+
+		return cb
+	}
+}
+
+type Interceptor struct {
+	Resolver Resolver
+	Amount   AmountExtractor
+
+	// OnError is called when circuit breaker fails, call still goes through
+	OnError func(fullMethod string, err error)
+}
+
+func New(resolver Resolver) *Interceptor {
+	return &Interceptor{
+		Resolver: resolver,
+		Amount:   CountCall,
+	}
+}
+
+// UnaryServerInterceptor rejects tripped calls with codes.Unavailable and calls over threshold with codes.ResourceExhausted
+func (i *Interceptor) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		amount, err := i.Amount(ctx, info.FullMethod, req)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		cb, err := i.guard(ctx, info.FullMethod, amount)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := handler(ctx, req)
+		i.record(ctx, cb, info.FullMethod, amount, err)
+
+		return res, err
+	}
+}
+
+// StreamServerInterceptor guards every stream as a call with amount 1
+func (i *Interceptor) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+
+		cb, err := i.guard(ctx, info.FullMethod, 1)
+		if err != nil {
+			return err
+		}
+
+		err = handler(srv, ss)
+		i.record(ctx, cb, info.FullMethod, 1, err)
+
+		return err
+	}
+}
+
+// UnaryClientInterceptor fails fast before the call leaves the client
+func (i *Interceptor) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		amount, err := i.Amount(ctx, method, req)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		cb, err := i.guard(ctx, method, amount)
+		if err != nil {
+			return err
+		}
+
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		i.record(ctx, cb, method, amount, err)
+
+		return err
+	}
+}
+
+// StreamClientInterceptor guards every stream as a call with amount 1
+func (i *Interceptor) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cb, err := i.guard(ctx, method, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		i.record(ctx, cb, method, 1, err)
+
+		return stream, err
+	}
+}
+
+// guard returns status error when amount would exceed the threshold of the circuit breaker of fullMethod or it is open
+func (i *Interceptor) guard(ctx context.Context, fullMethod string, amount int64) (circuitbreaker.CircuitBreaker, error) {
+	cb := i.Resolver(fullMethod)
+
+	err := cb.Guard(ctx, amount)
+	switch {
+	case errors.Is(err, circuitbreaker.ErrThresholdExceeded):
+		return cb, status.Errorf(codes.ResourceExhausted, "circuit breaker of %s exceeds threshold", fullMethod)
+	case errors.Is(err, circuitbreaker.ErrCircuitOpen):
+		return cb, status.Errorf(codes.Unavailable, "circuit breaker of %s is open", fullMethod)
+	case err != nil:
+		i.handleError(fullMethod, err)
+	}
+
+	return cb, nil
+}
+
+// record reports the outcome of the call and records its amount
+func (i *Interceptor) record(ctx context.Context, cb circuitbreaker.CircuitBreaker, fullMethod string, amount int64, callErr error) {
+	if err := cb.RecordCall(ctx, !isFailure(callErr), amount); err != nil {
+		i.handleError(fullMethod, err)
+	}
+}
+
+func (i *Interceptor) handleError(fullMethod string, err error) {
+	if i.OnError != nil {
+		i.OnError(fullMethod, err)
+	}
+}
+
+// isFailure tells whether err means the service is unhealthy, errors caused by the caller such as
+// InvalidArgument, NotFound or Canceled are not failures
+func isFailure(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.DeadlineExceeded, codes.Internal, codes.Unavailable, codes.Unknown, codes.DataLoss:
+		return true
+	}
+
+	return false
+}
+
+// CountCall counts every call as 1
+func CountCall(ctx context.Context, fullMethod string, req interface{}) (int64, error) {
+	return 1, nil
+}
+
+// getFeatureName with format <feature_name>{<escaped full method>}, see circuitbreaker.DimensionFeatureName
+// example: payment{%2Fgrpc.health.v1.Health%2FCheck}
+func getFeatureName(featureName string, fullMethod string) string {
+	return circuitbreaker.DimensionFeatureName(featureName, fullMethod)
+}
@@ -0,0 +1,278 @@
+package grpcmw_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/grpcmw"
+)
+
+const (
+	checkMethod = "/grpc.health.v1.Health/Check"
+	watchMethod = "/grpc.health.v1.Health/Watch"
+)
+
+func newResolver(t *testing.T, threshold int64) grpcmw.Resolver {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return grpcmw.NewResolver(
+		[]*circuitbreaker.Bucket{
+			circuitbreaker.NewBucket(time.Minute),
+		},
+		circuitbreaker.NewRedisCache(client, 5*time.Minute),
+		time.Hour,
+		"test",
+		threshold,
+		10*time.Minute,
+	)
+}
+
+// newHealthClient serves health service through bufconn, with interceptors on server or client side
+func newHealthClient(t *testing.T, serverOpts []grpc.ServerOption, dialOpts []grpc.DialOption) healthpb.HealthClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(serverOpts...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	dialOpts = append(dialOpts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.Dial("bufnet", dialOpts...)
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestInterceptor_Unary(t *testing.T) {
+	type Request struct {
+		threshold int64
+		isTripped bool
+		calls     int
+	}
+
+	type Response struct {
+		codes []codes.Code
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Calls under threshold go through": {
+			request: Request{
+				threshold: 10,
+				calls:     2,
+			},
+			response: Response{
+				codes: []codes.Code{codes.OK, codes.OK},
+			},
+		},
+		"Calls over threshold are ResourceExhausted": {
+			request: Request{
+				threshold: 3,
+				calls:     4,
+			},
+			response: Response{
+				codes: []codes.Code{codes.OK, codes.OK, codes.ResourceExhausted, codes.ResourceExhausted},
+			},
+		},
+		"Tripped calls are Unavailable": {
+			request: Request{
+				threshold: 10,
+				isTripped: true,
+				calls:     1,
+			},
+			response: Response{
+				codes: []codes.Code{codes.Unavailable},
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		for _, side := range []string{"server", "client"} {
+			t.Run(name+" on "+side, func(t *testing.T) {
+				ctx := context.Background()
+				resolver := newResolver(t, tc.request.threshold)
+				if tc.request.isTripped {
+					assert.Nil(t, resolver(checkMethod).UpdateTrip(ctx, true))
+				}
+
+				interceptor := grpcmw.New(resolver)
+				var client healthpb.HealthClient
+				if side == "server" {
+					client = newHealthClient(t, []grpc.ServerOption{grpc.UnaryInterceptor(interceptor.UnaryServerInterceptor())}, nil)
+				} else {
+					client = newHealthClient(t, nil, []grpc.DialOption{grpc.WithUnaryInterceptor(interceptor.UnaryClientInterceptor())})
+				}
+
+				for _, code := range tc.response.codes {
+					_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+					assert.Equal(t, code, status.Code(err))
+				}
+			})
+		}
+	}
+}
+
+func TestInterceptor_Stream(t *testing.T) {
+	for _, side := range []string{"server", "client"} {
+		t.Run("Tripped stream is Unavailable on "+side, func(t *testing.T) {
+			ctx := context.Background()
+			resolver := newResolver(t, 10)
+			assert.Nil(t, resolver(watchMethod).UpdateTrip(ctx, true))
+
+			interceptor := grpcmw.New(resolver)
+			var client healthpb.HealthClient
+			if side == "server" {
+				client = newHealthClient(t, []grpc.ServerOption{grpc.StreamInterceptor(interceptor.StreamServerInterceptor())}, nil)
+			} else {
+				client = newHealthClient(t, nil, []grpc.DialOption{grpc.WithStreamInterceptor(interceptor.StreamClientInterceptor())})
+			}
+
+			stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			assert.Equal(t, codes.Unavailable, status.Code(err))
+
+			// other methods have their own circuit breaker
+			_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+			assert.Nil(t, err)
+		})
+	}
+
+	t.Run("Stream is recorded", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		resolver := newResolver(t, 10)
+
+		interceptor := grpcmw.New(resolver)
+		client := newHealthClient(t, nil, []grpc.DialOption{grpc.WithStreamInterceptor(interceptor.StreamClientInterceptor())})
+
+		stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+		assert.Nil(t, err)
+		_, err = stream.Recv()
+		assert.Nil(t, err)
+
+		windowValue, err := resolver(watchMethod).CalculateWindowValue(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), windowValue)
+	})
+}
+
+func TestInterceptor_RecordResult(t *testing.T) {
+	type Request struct {
+		handlerErr error
+		// expired cancels the call context before the handler returns
+		expired bool
+	}
+
+	type Response struct {
+		state circuitbreaker.State
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Success closes Half-Open": {
+			request: Request{},
+			response: Response{
+				state: circuitbreaker.StateClosed,
+			},
+		},
+		"Caller error closes Half-Open": {
+			request: Request{
+				handlerErr: status.Error(codes.NotFound, "unknown service"),
+			},
+			response: Response{
+				state: circuitbreaker.StateClosed,
+			},
+		},
+		"Canceled call closes Half-Open": {
+			request: Request{
+				handlerErr: context.Canceled,
+			},
+			response: Response{
+				state: circuitbreaker.StateClosed,
+			},
+		},
+		"Expired call opens Half-Open again": {
+			request: Request{
+				handlerErr: status.Error(codes.DeadlineExceeded, "deadline exceeded"),
+				expired:    true,
+			},
+			response: Response{
+				state: circuitbreaker.StateOpen,
+			},
+		},
+		"Unavailable opens Half-Open again": {
+			request: Request{
+				handlerErr: status.Error(codes.Unavailable, "downstream unavailable"),
+			},
+			response: Response{
+				state: circuitbreaker.StateOpen,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			resolver := newResolver(t, 10)
+			cb := resolver(checkMethod)
+
+			// zero open duration moves the tripped circuit breaker to Half-Open right away
+			cb.SetStateMachineConfig(circuitbreaker.StateMachineConfig{OpenDuration: 0, HalfOpenMaxProbes: 1, HalfOpenSuccessThreshold: 1})
+			assert.Nil(t, cb.UpdateTrip(ctx, true))
+			state, err := cb.GetState(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, circuitbreaker.StateHalfOpen, state)
+			cb.SetStateMachineConfig(circuitbreaker.StateMachineConfig{OpenDuration: time.Hour, HalfOpenMaxProbes: 1, HalfOpenSuccessThreshold: 1})
+
+			callCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			intercept := grpcmw.New(resolver).UnaryServerInterceptor()
+			_, err = intercept(callCtx, &healthpb.HealthCheckRequest{}, &grpc.UnaryServerInfo{FullMethod: checkMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				if tc.request.expired {
+					cancel()
+				}
+				return nil, tc.request.handlerErr
+			})
+			assert.Equal(t, tc.request.handlerErr, err)
+
+			state, err = cb.GetState(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.state, state)
+		})
+	}
+}
+
+func TestNewResolver_FeatureName(t *testing.T) {
+	resolver := newResolver(t, 10)
+
+	assert.Equal(t, "test{%2Fgrpc.health.v1.Health%2FCheck}", resolver(checkMethod).GetFeatureName())
+	// methods differing only in separators keep their own circuit breaker keys
+	assert.NotEqual(t, resolver("/a.S/B_C").GetFeatureName(), resolver("/a.S_B/C").GetFeatureName())
+}
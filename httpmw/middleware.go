@@ -0,0 +1,182 @@
+package httpmw
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	circuitbreaker "go-circuit-breaker"
+)
+
+var (
+	DefaultRetryAfter = time.Minute
+	// MaxBodyBytes caps how much of the body JSONBodyAmount reads
+	MaxBodyBytes int64 = 1 << 20
+
+	ErrInvalidAmount = errors.New("invalid amount")
+	ErrMissingAmount = errors.New("missing amount")
+)
+
+// AmountExtractor returns the amount a request counts for
+type AmountExtractor func(r *http.Request) (int64, error)
+
+type Middleware struct {
+	CircuitBreaker circuitbreaker.CircuitBreaker
+	Amount         AmountExtractor
+	RetryAfter     time.Duration
+
+	// OnError is called when circuit breaker fails, request is still served
+	OnError func(r *http.Request, err error)
+}
+
+func New(
+	cb circuitbreaker.CircuitBreaker,
+	amount AmountExtractor,
+) *Middleware {
+	return &Middleware{
+		CircuitBreaker: cb,
+		Amount:         amount,
+		RetryAfter:     DefaultRetryAfter,
+	}
+}
+
+// Handler sheds requests with 429 while amount would exceed the threshold and with 503 while circuit breaker is open,
+// otherwise it serves the request, reports a 5xx status as failure to the state machine and records its amount afterwards
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		amount, err := m.Amount(r)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = m.CircuitBreaker.Guard(ctx, amount)
+		switch {
+		case errors.Is(err, circuitbreaker.ErrThresholdExceeded):
+			m.reject(w, http.StatusTooManyRequests)
+			return
+		case errors.Is(err, circuitbreaker.ErrCircuitOpen):
+			m.reject(w, http.StatusServiceUnavailable)
+			return
+		case err != nil:
+			m.handleError(r, err)
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		if err := m.CircuitBreaker.RecordCall(ctx, recorder.status < http.StatusInternalServerError, amount); err != nil {
+			m.handleError(r, err)
+		}
+	})
+}
+
+func (m *Middleware) reject(w http.ResponseWriter, status int) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(m.RetryAfter.Seconds())))
+	http.Error(w, http.StatusText(status), status)
+}
+
+func (m *Middleware) handleError(r *http.Request, err error) {
+	if m.OnError != nil {
+		m.OnError(r, err)
+	}
+}
+
+// statusRecorder keeps the status written by the handler, it is 200 when the handler only writes the body
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the original ResponseWriter
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// CountRequest counts every request as 1
+func CountRequest(r *http.Request) (int64, error) {
+	return 1, nil
+}
+
+// HeaderAmount parses the amount from header
+func HeaderAmount(header string) AmountExtractor {
+	return func(r *http.Request) (int64, error) {
+		value := r.Header.Get(header)
+		if value == "" {
+			return 0, fmt.Errorf("%w: header %s", ErrMissingAmount, header)
+		}
+
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return checkAmount(amount)
+	}
+}
+
+// JSONBodyAmount parses the amount from a top level field of a json body, body is left readable for the handler
+// at most MaxBodyBytes are read, a larger body returns *http.MaxBytesError
+func JSONBodyAmount(field string) AmountExtractor {
+	return func(r *http.Request) (int64, error) {
+		if r.Body == nil {
+			return 0, fmt.Errorf("%w: field %s", ErrMissingAmount, field)
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, MaxBodyBytes))
+		if err != nil {
+			return 0, err
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return 0, err
+		}
+		value, found := fields[field]
+		if !found {
+			return 0, fmt.Errorf("%w: field %s", ErrMissingAmount, field)
+		}
+
+		var amount int64
+		if err := json.Unmarshal(value, &amount); err != nil {
+			return 0, err
+		}
+		return checkAmount(amount)
+	}
+}
+
+// checkAmount rejects amounts that are not positive, they would lower the window value instead of counting toward it
+func checkAmount(amount int64) (int64, error) {
+	if amount <= 0 {
+		return 0, fmt.Errorf("%w: %d must be positive", ErrInvalidAmount, amount)
+	}
+
+	return amount, nil
+}
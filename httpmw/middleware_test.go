@@ -0,0 +1,212 @@
+package httpmw_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/httpmw"
+	"go-circuit-breaker/mock"
+)
+
+var (
+	ErrUnexpectedRedis = errors.New("unexpected redis error")
+)
+
+func TestMiddleware_Handler(t *testing.T) {
+	type Request struct {
+		request *http.Request
+		amount  httpmw.AmountExtractor
+		// status written by the handler, 200 when zero
+		status int
+	}
+
+	type Response struct {
+		status     int
+		retryAfter string
+		served     bool
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+		mockFn   func(m *mock.MockCircuitBreaker)
+	}{
+		"Request is served and recorded": {
+			request: Request{
+				request: httptest.NewRequest(http.MethodGet, "/", nil),
+				amount:  httpmw.CountRequest,
+			},
+			response: Response{
+				status: http.StatusOK,
+				served: true,
+			},
+			mockFn: func(m *mock.MockCircuitBreaker) {
+				m.EXPECT().Guard(gomock.Any(), int64(1)).Return(nil)
+				m.EXPECT().RecordCall(gomock.Any(), true, int64(1)).Return(nil)
+			},
+		},
+		"Server error is reported as failure": {
+			request: Request{
+				request: httptest.NewRequest(http.MethodGet, "/", nil),
+				amount:  httpmw.CountRequest,
+				status:  http.StatusBadGateway,
+			},
+			response: Response{
+				status: http.StatusBadGateway,
+				served: true,
+			},
+			mockFn: func(m *mock.MockCircuitBreaker) {
+				m.EXPECT().Guard(gomock.Any(), int64(1)).Return(nil)
+				m.EXPECT().RecordCall(gomock.Any(), false, int64(1)).Return(nil)
+			},
+		},
+		"Open circuit breaker returns 503": {
+			request: Request{
+				request: httptest.NewRequest(http.MethodGet, "/", nil),
+				amount:  httpmw.CountRequest,
+			},
+			response: Response{
+				status:     http.StatusServiceUnavailable,
+				retryAfter: "30",
+			},
+			mockFn: func(m *mock.MockCircuitBreaker) {
+				m.EXPECT().Guard(gomock.Any(), int64(1)).Return(circuitbreaker.ErrCircuitOpen)
+			},
+		},
+		"Exceeding threshold returns 429": {
+			request: Request{
+				request: func() *http.Request {
+					r := httptest.NewRequest(http.MethodPost, "/", nil)
+					r.Header.Set("X-Amount", "500")
+					return r
+				}(),
+				amount: httpmw.HeaderAmount("X-Amount"),
+			},
+			response: Response{
+				status:     http.StatusTooManyRequests,
+				retryAfter: "30",
+			},
+			mockFn: func(m *mock.MockCircuitBreaker) {
+				m.EXPECT().Guard(gomock.Any(), int64(500)).Return(circuitbreaker.ErrThresholdExceeded)
+			},
+		},
+		"Amount from json body": {
+			request: Request{
+				request: httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 250, "currency": "IDR"}`)),
+				amount:  httpmw.JSONBodyAmount("amount"),
+			},
+			response: Response{
+				status: http.StatusOK,
+				served: true,
+			},
+			mockFn: func(m *mock.MockCircuitBreaker) {
+				m.EXPECT().Guard(gomock.Any(), int64(250)).Return(nil)
+				m.EXPECT().RecordCall(gomock.Any(), true, int64(250)).Return(nil)
+			},
+		},
+		"Missing amount returns 400": {
+			request: Request{
+				request: httptest.NewRequest(http.MethodPost, "/", nil),
+				amount:  httpmw.HeaderAmount("X-Amount"),
+			},
+			response: Response{
+				status: http.StatusBadRequest,
+			},
+			mockFn: func(m *mock.MockCircuitBreaker) {},
+		},
+		"Negative amount returns 400": {
+			request: Request{
+				request: func() *http.Request {
+					r := httptest.NewRequest(http.MethodPost, "/", nil)
+					r.Header.Set("X-Amount", "-500")
+					return r
+				}(),
+				amount: httpmw.HeaderAmount("X-Amount"),
+			},
+			response: Response{
+				status: http.StatusBadRequest,
+			},
+			mockFn: func(m *mock.MockCircuitBreaker) {},
+		},
+		"Body too large returns 413": {
+			request: Request{
+				request: httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 250, "memo": "`+strings.Repeat("a", int(httpmw.MaxBodyBytes))+`"}`)),
+				amount:  httpmw.JSONBodyAmount("amount"),
+			},
+			response: Response{
+				status: http.StatusRequestEntityTooLarge,
+			},
+			mockFn: func(m *mock.MockCircuitBreaker) {},
+		},
+		"Cache error fails open": {
+			request: Request{
+				request: httptest.NewRequest(http.MethodGet, "/", nil),
+				amount:  httpmw.CountRequest,
+			},
+			response: Response{
+				status: http.StatusOK,
+				served: true,
+			},
+			mockFn: func(m *mock.MockCircuitBreaker) {
+				m.EXPECT().Guard(gomock.Any(), int64(1)).Return(ErrUnexpectedRedis)
+				m.EXPECT().RecordCall(gomock.Any(), true, int64(1)).Return(ErrUnexpectedRedis)
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cb := mock.NewMockCircuitBreaker(ctrl)
+			tc.mockFn(cb)
+
+			served := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				served = true
+				if tc.request.status != 0 {
+					w.WriteHeader(tc.request.status)
+				}
+			})
+
+			mw := httpmw.New(cb, tc.request.amount)
+			mw.RetryAfter = 30 * time.Second
+
+			recorder := httptest.NewRecorder()
+			mw.Handler(next).ServeHTTP(recorder, tc.request.request)
+
+			assert.Equal(t, tc.response.status, recorder.Code)
+			assert.Equal(t, tc.response.retryAfter, recorder.Header().Get("Retry-After"))
+			assert.Equal(t, tc.response.served, served)
+		})
+	}
+}
+
+func TestJSONBodyAmount(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 250}`))
+
+	amount, err := httpmw.JSONBodyAmount("amount")(r)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(250), amount)
+
+	// body is still readable by the handler
+	body, err := io.ReadAll(r.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"amount": 250}`, string(body))
+
+	_, err = httpmw.JSONBodyAmount("amount")(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`)))
+	assert.ErrorIs(t, err, httpmw.ErrMissingAmount)
+
+	_, err = httpmw.JSONBodyAmount("amount")(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 0}`)))
+	assert.ErrorIs(t, err, httpmw.ErrInvalidAmount)
+}
@@ -0,0 +1,115 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	circuitbreaker "go-circuit-breaker"
+)
+
+var (
+	DefaultMaxHosts = 1000
+)
+
+// HostOpenError is returned while circuit breaker of the destination host is open
+type HostOpenError struct {
+	Host string
+}
+
+func (e *HostOpenError) Error() string {
+	return fmt.Sprintf("circuit open for host %s", e.Host)
+}
+
+func (e *HostOpenError) Unwrap() error {
+	return circuitbreaker.ErrCircuitOpen
+}
+
+// Transport is http.RoundTripper keeping one circuit breaker per destination host in a KeyedBreaker
+// 5xx responses and transport errors are recorded as failures, once the threshold of failures happen within the window
+// the host's circuit breaker trips and requests fail fast with HostOpenError
+type Transport struct {
+	Base  http.RoundTripper
+	Hosts circuitbreaker.KeyedBreaker
+
+	// Configure is called once for every new host circuit breaker, again after it has been evicted
+	Configure func(host string, cb circuitbreaker.CircuitBreaker)
+	// OnError is called when circuit breaker fails, request still goes through
+	OnError func(req *http.Request, err error)
+}
+
+// New creates Transport from the same options as circuitbreaker.New, keeping at most maxHosts circuit breakers in memory
+// every host circuit breaker trips automatically
+func New(base http.RoundTripper, featureName string, maxHosts int, opts ...circuitbreaker.Option) (*Transport, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	hosts, err := circuitbreaker.NewKeyedBreaker(featureName, maxHosts, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Transport{
+		Base:  base,
+		Hosts: hosts,
+	}
+	hosts.SetConfigure(t.configure)
+
+	return t, nil
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	host := req.URL.Host
+
+	cb, err := t.Hosts.Breaker(host)
+	if err != nil {
+		t.handleError(req, err)
+		return t.Base.RoundTrip(req)
+	}
+
+	allowed, err := cb.Allow(ctx)
+	if err != nil {
+		t.handleError(req, err)
+		allowed = true
+	}
+	if !allowed {
+		return nil, &HostOpenError{Host: host}
+	}
+
+	res, err := t.Base.RoundTrip(req)
+	isFailure := err == nil && res.StatusCode >= http.StatusInternalServerError || err != nil && !isCanceled(ctx)
+
+	// failures are the recorded amount, auto trip opens the circuit once they cross the threshold
+	failures := int64(0)
+	if isFailure {
+		failures = 1
+	}
+	if err := cb.RecordCall(ctx, !isFailure, failures); err != nil {
+		t.handleError(req, err)
+	}
+
+	return res, err
+}
+
+// configure trips every host circuit breaker automatically before Configure sees it
+func (t *Transport) configure(host string, cb circuitbreaker.CircuitBreaker) {
+	cb.SetAutoTrip(true)
+	if t.Configure != nil {
+		t.Configure(host, cb)
+	}
+}
+
+func (t *Transport) handleError(req *http.Request, err error) {
+	if t.OnError != nil {
+		t.OnError(req, err)
+	}
+}
+
+// isCanceled tells whether the caller canceled the request, which says nothing about the host
+// an expired deadline, e.g. http.Client.Timeout, is a host that didn't answer in time and counts as failure
+func isCanceled(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
+}
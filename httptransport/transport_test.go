@@ -0,0 +1,256 @@
+package httptransport_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/httptransport"
+)
+
+var (
+	ErrConnectionRefused = errors.New("connection refused")
+	ErrRequestCanceled   = fmt.Errorf("net/http: request canceled: %w", context.Canceled)
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newTransport(t *testing.T, base http.RoundTripper, threshold int64) *httptransport.Transport {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	transport, err := httptransport.New(
+		base,
+		"partner",
+		httptransport.DefaultMaxHosts,
+		circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Minute)),
+		circuitbreaker.WithCache(circuitbreaker.NewRedisCache(client, 5*time.Minute)),
+		circuitbreaker.WithCacheTTL(time.Hour),
+		circuitbreaker.WithThreshold(threshold),
+		circuitbreaker.WithWindow(10*time.Minute),
+	)
+	assert.Nil(t, err)
+
+	return transport
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	type Request struct {
+		status    int
+		err       error
+		threshold int64
+		calls     int
+		// canceled cancels the request context while the request is in flight
+		canceled bool
+		// timeout expires the request context while the request is in flight
+		timeout bool
+	}
+
+	type Response struct {
+		baseCalls int
+		openCalls int
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Successful responses never trip": {
+			request: Request{
+				status:    http.StatusOK,
+				threshold: 2,
+				calls:     5,
+			},
+			response: Response{
+				baseCalls: 5,
+				openCalls: 0,
+			},
+		},
+		"Client errors never trip": {
+			request: Request{
+				status:    http.StatusNotFound,
+				threshold: 2,
+				calls:     5,
+			},
+			response: Response{
+				baseCalls: 5,
+				openCalls: 0,
+			},
+		},
+		"5xx responses trip the host": {
+			request: Request{
+				status:    http.StatusBadGateway,
+				threshold: 2,
+				calls:     5,
+			},
+			response: Response{
+				baseCalls: 2,
+				openCalls: 3,
+			},
+		},
+		"Transport errors trip the host": {
+			request: Request{
+				err:       ErrConnectionRefused,
+				threshold: 3,
+				calls:     5,
+			},
+			response: Response{
+				baseCalls: 3,
+				openCalls: 2,
+			},
+		},
+		"Canceled requests never trip": {
+			request: Request{
+				err:       ErrRequestCanceled,
+				threshold: 2,
+				calls:     5,
+				canceled:  true,
+			},
+			response: Response{
+				baseCalls: 5,
+				openCalls: 0,
+			},
+		},
+		"Timed out requests trip the host": {
+			request: Request{
+				threshold: 2,
+				calls:     5,
+				timeout:   true,
+			},
+			response: Response{
+				baseCalls: 2,
+				openCalls: 3,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			baseCalls := 0
+			var cancel context.CancelFunc
+			base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				baseCalls++
+				if tc.request.canceled {
+					cancel()
+				}
+				if tc.request.timeout {
+					<-req.Context().Done()
+					return nil, req.Context().Err()
+				}
+				if tc.request.err != nil {
+					return nil, tc.request.err
+				}
+				return &http.Response{StatusCode: tc.request.status, Body: http.NoBody, Request: req}, nil
+			})
+
+			transport := newTransport(t, base, tc.request.threshold)
+			transport.Configure = func(host string, cb circuitbreaker.CircuitBreaker) {
+				cb.SetStateMachineConfig(circuitbreaker.StateMachineConfig{OpenDuration: time.Hour, HalfOpenMaxProbes: 1, HalfOpenSuccessThreshold: 1})
+			}
+
+			openCalls := 0
+			for i := 0; i < tc.request.calls; i++ {
+				ctx, cancelCtx := context.WithCancel(context.Background())
+				if tc.request.timeout {
+					ctx, cancelCtx = context.WithTimeout(context.Background(), 20*time.Millisecond)
+				}
+				cancel = cancelCtx
+
+				req := httptest.NewRequest(http.MethodGet, "http://bank.example.com/transfer", nil).WithContext(ctx)
+				_, err := transport.RoundTrip(req)
+				cancel()
+
+				var openErr *httptransport.HostOpenError
+				if errors.As(err, &openErr) {
+					assert.Equal(t, "bank.example.com", openErr.Host)
+					assert.ErrorIs(t, err, circuitbreaker.ErrCircuitOpen)
+					openCalls++
+				}
+			}
+
+			assert.Equal(t, tc.response.baseCalls, baseCalls)
+			assert.Equal(t, tc.response.openCalls, openCalls)
+		})
+	}
+}
+
+func TestTransport_CircuitBreakerPerHost(t *testing.T) {
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "down.example.com" {
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Request: req}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	transport := newTransport(t, base, 1)
+	client := &http.Client{Transport: transport}
+
+	_, err := client.Get("http://down.example.com/")
+	assert.Nil(t, err)
+	_, err = client.Get("http://down.example.com/")
+	assert.ErrorIs(t, err, circuitbreaker.ErrCircuitOpen)
+
+	res, err := client.Get("http://up.example.com/")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	up, err := transport.Hosts.Breaker("up.example.com")
+	assert.Nil(t, err)
+	down, err := transport.Hosts.Breaker("down.example.com")
+	assert.Nil(t, err)
+	assert.NotSame(t, up, down)
+	assert.Equal(t, "partner{up.example.com}", up.GetFeatureName())
+}
+
+func TestTransport_OnError(t *testing.T) {
+	baseCalls := 0
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		baseCalls++
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	transport := newTransport(t, base, 1)
+
+	errs := []error{}
+	transport.OnError = func(req *http.Request, err error) {
+		errs = append(errs, err)
+	}
+
+	// the cache can't be reached with a canceled context, the request still goes through
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err := transport.RoundTrip(httptest.NewRequest(http.MethodGet, "http://bank.example.com/", nil).WithContext(ctx))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 1, baseCalls)
+	assert.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], context.Canceled)
+}
+
+func TestTransport_Configure(t *testing.T) {
+	transport := newTransport(t, http.DefaultTransport, 1)
+
+	configured := []string{}
+	transport.Configure = func(host string, cb circuitbreaker.CircuitBreaker) {
+		configured = append(configured, host)
+	}
+
+	for _, host := range []string{"a.example.com", "b.example.com", "a.example.com"} {
+		_, err := transport.Hosts.Breaker(host)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 2, transport.Hosts.Len())
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, configured)
+}
@@ -0,0 +1,274 @@
+package circuitbreaker
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrEmptyKey = errors.New("empty dimension key")
+)
+
+// KeyedBreaker is one circuit breaker definition applied to many dimensions, e.g. the same 24h limit per user
+// every call takes the dimension key, its circuit breaker is created on first use
+type KeyedBreaker interface {
+	Allow(ctx context.Context, key string) (bool, error)
+	Breaker(key string) (CircuitBreaker, error)
+	CalculateWindowValue(ctx context.Context, key string) (int64, error)
+	Execute(ctx context.Context, key string, amount int64, fn func(ctx context.Context) error) error
+	GetState(ctx context.Context, key string) (State, error)
+	GetTrip(ctx context.Context, key string) (bool, error)
+	IsExceedingThreshold(ctx context.Context, key string, amount int64) (bool, error)
+	IsExceedingWarningThreshold(ctx context.Context, key string, amount int64) (bool, error)
+	Len() int
+	Record(ctx context.Context, key string, amount int64) (Transitions, error)
+	RecordResult(ctx context.Context, key string, success bool) error
+	Reserve(ctx context.Context, key string, amount int64) (Reservation, error)
+	SetConfigure(configure func(key string, cb CircuitBreaker))
+	TryConsume(ctx context.Context, key string, amount int64) (bool, int64, error)
+	UpdateLatestBucketsValue(ctx context.Context, key string, amount int64) error
+	UpdateTrip(ctx context.Context, key string, isTripped bool) error
+}
+
+type keyedEntry struct {
+	key            string
+	circuitBreaker CircuitBreaker
+}
+
+type keyedBreaker struct {
+	Template *circuitBreaker
+	MaxKeys  int
+
+	// Configure is called once for every new dimension circuit breaker, again after it has been evicted
+	Configure func(key string, cb CircuitBreaker)
+
+	entries map[string]*list.Element
+	lru     *list.List
+	mutex   sync.Mutex
+}
+
+// NewKeyedBreaker creates KeyedBreaker from the same options as New, keeping at most maxKeys circuit breakers in memory
+// the least recently used one is evicted first, its buckets and state stay in the cache so nothing is lost
+func NewKeyedBreaker(featureName string, maxKeys int, opts ...Option) (KeyedBreaker, error) {
+	template := newCircuitBreakerFromOptions(featureName, opts...)
+	if maxKeys <= 0 {
+		return nil, &ConfigError{Field: "max keys", Message: fmt.Sprintf("%d must be positive", maxKeys)}
+	}
+	if err := template.validateWithoutCurrency(); err != nil {
+		return nil, err
+	}
+	template.init()
+
+	return &keyedBreaker{
+		Template: template,
+		MaxKeys:  maxKeys,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}, nil
+}
+
+func (k *keyedBreaker) Allow(ctx context.Context, key string) (bool, error) {
+	cb, err := k.Breaker(key)
+	if err != nil {
+		return false, err
+	}
+
+	return cb.Allow(ctx)
+}
+
+// Breaker returns circuit breaker of key, creating it on first use and marking it as recently used
+func (k *keyedBreaker) Breaker(key string) (CircuitBreaker, error) {
+	if key == "" {
+		return nil, ErrEmptyKey
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if element, found := k.entries[key]; found {
+		k.lru.MoveToFront(element)
+		return element.Value.(*keyedEntry).circuitBreaker, nil
+	}
+
+	cb := k.newBreaker(key)
+	if k.Configure != nil {
+		k.Configure(key, cb)
+	}
+	k.entries[key] = k.lru.PushFront(&keyedEntry{key: key, circuitBreaker: cb})
+
+	for k.lru.Len() > k.MaxKeys {
+		oldest := k.lru.Back()
+		k.lru.Remove(oldest)
+		delete(k.entries, oldest.Value.(*keyedEntry).key)
+	}
+
+	return cb, nil
+}
+
+func (k *keyedBreaker) CalculateWindowValue(ctx context.Context, key string) (int64, error) {
+	cb, err := k.Breaker(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return cb.CalculateWindowValue(ctx)
+}
+
+func (k *keyedBreaker) Execute(ctx context.Context, key string, amount int64, fn func(ctx context.Context) error) error {
+	cb, err := k.Breaker(key)
+	if err != nil {
+		return err
+	}
+
+	return cb.Execute(ctx, amount, fn)
+}
+
+func (k *keyedBreaker) GetState(ctx context.Context, key string) (State, error) {
+	cb, err := k.Breaker(key)
+	if err != nil {
+		return StateClosed, err
+	}
+
+	return cb.GetState(ctx)
+}
+
+func (k *keyedBreaker) GetTrip(ctx context.Context, key string) (bool, error) {
+	cb, err := k.Breaker(key)
+	if err != nil {
+		return false, err
+	}
+
+	return cb.GetTrip(ctx)
+}
+
+func (k *keyedBreaker) IsExceedingThreshold(ctx context.Context, key string, amount int64) (bool, error) {
+	cb, err := k.Breaker(key)
+	if err != nil {
+		return false, err
+	}
+
+	return cb.IsExceedingThreshold(ctx, amount)
+}
+
+func (k *keyedBreaker) IsExceedingWarningThreshold(ctx context.Context, key string, amount int64) (bool, error) {
+	cb, err := k.Breaker(key)
+	if err != nil {
+		return false, err
+	}
+
+	return cb.IsExceedingWarningThreshold(ctx, amount)
+}
+
+// Len returns how many circuit breakers are kept in memory
+func (k *keyedBreaker) Len() int {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	return k.lru.Len()
+}
+
+func (k *keyedBreaker) Record(ctx context.Context, key string, amount int64) (Transitions, error) {
+	cb, err := k.Breaker(key)
+	if err != nil {
+		return Transitions{}, err
+	}
+
+	return cb.Record(ctx, amount)
+}
+
+func (k *keyedBreaker) RecordResult(ctx context.Context, key string, success bool) error {
+	cb, err := k.Breaker(key)
+	if err != nil {
+		return err
+	}
+
+	return cb.RecordResult(ctx, success)
+}
+
+func (k *keyedBreaker) Reserve(ctx context.Context, key string, amount int64) (Reservation, error) {
+	cb, err := k.Breaker(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cb.Reserve(ctx, amount)
+}
+
+// SetConfigure sets what is called for every new dimension circuit breaker, e.g. to set its own threshold
+// circuit breakers already in memory are not configured again
+func (k *keyedBreaker) SetConfigure(configure func(key string, cb CircuitBreaker)) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	k.Configure = configure
+}
+
+func (k *keyedBreaker) TryConsume(ctx context.Context, key string, amount int64) (bool, int64, error) {
+	cb, err := k.Breaker(key)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return cb.TryConsume(ctx, amount)
+}
+
+func (k *keyedBreaker) UpdateLatestBucketsValue(ctx context.Context, key string, amount int64) error {
+	cb, err := k.Breaker(key)
+	if err != nil {
+		return err
+	}
+
+	return cb.UpdateLatestBucketsValue(ctx, amount)
+}
+
+func (k *keyedBreaker) UpdateTrip(ctx context.Context, key string, isTripped bool) error {
+	cb, err := k.Breaker(key)
+	if err != nil {
+		return err
+	}
+
+	return cb.UpdateTrip(ctx, isTripped)
+}
+
+// newBreaker copies the template with the dimension in its feature name, so every key of the dimension includes it
+// buckets are copied, init sorts them in place
+func (k *keyedBreaker) newBreaker(key string) *circuitBreaker {
+	cb := *k.Template
+	cb.Buckets = append([]*Bucket{}, k.Template.Buckets...)
+	cb.FeatureName = DimensionFeatureName(k.Template.FeatureName, key)
+	cb.init()
+
+	return &cb
+}
+
+// DimensionFeatureName with format <feature_name>{<escaped key>}, distinct keys always give distinct feature names
+// the key is a redis cluster hash tag, so every key of a dimension lands in the same slot as the lua script needs
+// example: loan_disbursement{user:42}
+func DimensionFeatureName(featureName string, key string) string {
+	return fmt.Sprintf("%s{%s}", featureName, escapeDimensionKey(key))
+}
+
+// escapeDimensionKey percent-encodes every byte that could break a key apart, '-' separates key parts and
+// braces would end the hash tag, so distinct dimension keys never share a redis key
+// example: merchant-7 {eu} -> merchant%2D7%20%7Beu%7D
+func escapeDimensionKey(key string) string {
+	var builder strings.Builder
+	for i := 0; i < len(key); i++ {
+		b := key[i]
+		if isDimensionKeyByte(b) {
+			builder.WriteByte(b)
+			continue
+		}
+		fmt.Fprintf(&builder, "%%%02X", b)
+	}
+
+	return builder.String()
+}
+
+func isDimensionKeyByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || strings.IndexByte("_.:@", b) >= 0
+}
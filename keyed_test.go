@@ -0,0 +1,155 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/cbtest"
+)
+
+func TestKeyedBreaker_Keys(t *testing.T) {
+	type Request struct {
+		key string
+	}
+
+	type Response struct {
+		keys []string
+		err  error
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Plain key": {
+			request: Request{
+				key: "user:42",
+			},
+			response: Response{
+				keys: []string{
+					"cb-loan_disbursement{user:42}-24h-1h-20230509100000",
+					"cb-loan_disbursement{user:42}-24h-1m-20230509104200",
+				},
+			},
+		},
+		"Separators and braces are escaped": {
+			request: Request{
+				key: "merchant-7 {eu}",
+			},
+			response: Response{
+				keys: []string{
+					"cb-loan_disbursement{merchant%2D7%20%7Beu%7D}-24h-1h-20230509100000",
+					"cb-loan_disbursement{merchant%2D7%20%7Beu%7D}-24h-1m-20230509104200",
+				},
+			},
+		},
+		"Empty key": {
+			request: Request{
+				key: "",
+			},
+			response: Response{
+				keys: []string{},
+				err:  circuitbreaker.ErrEmptyKey,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			server, client := newRedisClient(t)
+			keyed := newKeyedBreaker(t, client, 10)
+
+			err := keyed.UpdateLatestBucketsValue(ctx, tc.request.key, 10)
+			assert.ErrorIs(t, err, tc.response.err)
+
+			keys := server.Keys()
+			sort.Strings(keys)
+			assert.Equal(t, tc.response.keys, keys)
+		})
+	}
+}
+
+func TestKeyedBreaker_Dimensions(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	keyed := newKeyedBreaker(t, client, 10)
+
+	allowed, _, err := keyed.TryConsume(ctx, "user:1", 80)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	// every dimension has its own window and trip
+	isExceeding, err := keyed.IsExceedingThreshold(ctx, "user:1", 30)
+	assert.Nil(t, err)
+	assert.True(t, isExceeding)
+	isExceeding, err = keyed.IsExceedingThreshold(ctx, "user:2", 30)
+	assert.Nil(t, err)
+	assert.False(t, isExceeding)
+
+	assert.Nil(t, keyed.UpdateTrip(ctx, "user:1", true))
+	isTripped, err := keyed.GetTrip(ctx, "user:1")
+	assert.Nil(t, err)
+	assert.True(t, isTripped)
+	allowed, err = keyed.Allow(ctx, "user:2")
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+}
+
+func TestKeyedBreaker_Eviction(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	keyed := newKeyedBreaker(t, client, 2)
+
+	configured := []string{}
+	keyed.SetConfigure(func(key string, cb circuitbreaker.CircuitBreaker) {
+		configured = append(configured, key)
+	})
+
+	assert.Nil(t, keyed.UpdateLatestBucketsValue(ctx, "user:1", 10))
+	assert.Nil(t, keyed.UpdateLatestBucketsValue(ctx, "user:2", 20))
+	// user:1 is used again, so user:2 is the least recently used one
+	assert.Nil(t, keyed.UpdateLatestBucketsValue(ctx, "user:1", 10))
+	assert.Nil(t, keyed.UpdateLatestBucketsValue(ctx, "user:3", 30))
+	assert.Equal(t, 2, keyed.Len())
+
+	// evicted dimension is created again and still sees its window in the cache
+	windowValue, err := keyed.CalculateWindowValue(ctx, "user:2")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(20), windowValue)
+	assert.Equal(t, 2, keyed.Len())
+	assert.Equal(t, []string{"user:1", "user:2", "user:3", "user:2"}, configured)
+}
+
+func TestKeyedBreaker_New(t *testing.T) {
+	_, client := newRedisClient(t)
+
+	_, err := circuitbreaker.NewKeyedBreaker("loan_disbursement", 0, circuitbreaker.WithCache(circuitbreaker.NewRedisCache(client, time.Hour)))
+	assert.ErrorIs(t, err, circuitbreaker.ErrInvalidConfig)
+
+	_, err = circuitbreaker.NewKeyedBreaker("loan_disbursement", 10)
+	assert.ErrorIs(t, err, circuitbreaker.ErrInvalidConfig)
+}
+
+func newKeyedBreaker(t *testing.T, client redis.UniversalClient, maxKeys int) circuitbreaker.KeyedBreaker {
+	t.Helper()
+
+	keyed, err := circuitbreaker.NewKeyedBreaker(
+		"loan_disbursement",
+		maxKeys,
+		circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Hour), circuitbreaker.NewBucket(time.Minute)),
+		circuitbreaker.WithCache(circuitbreaker.NewRedisCache(client, 5*time.Minute)),
+		circuitbreaker.WithCacheTTL(28*time.Hour),
+		circuitbreaker.WithClock(cbtest.NewClock(time.Date(2023, time.May, 9, 10, 42, 0, 0, time.UTC))),
+		circuitbreaker.WithThreshold(100),
+	)
+	assert.Nil(t, err)
+
+	return keyed
+}
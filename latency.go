@@ -0,0 +1,175 @@
+package circuitbreaker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+var (
+	DefaultLatencyBounds = []time.Duration{
+		time.Millisecond,
+		2 * time.Millisecond,
+		5 * time.Millisecond,
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		50 * time.Millisecond,
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		500 * time.Millisecond,
+		time.Second,
+		2 * time.Second,
+		5 * time.Second,
+		10 * time.Second,
+	}
+)
+
+// latencyOverflow names the histogram bin above the largest bound
+const latencyOverflow = "inf"
+
+// LatencyConfig stores latency as a fixed bucket histogram, one series per bound plus an overflow series
+// Bounds are ascending upper bounds and default to DefaultLatencyBounds
+// RecordLatency trips the circuit once the Percentile latency goes above Target with at least MinimumVolume calls,
+// zero Percentile or Target disables tripping
+type LatencyConfig struct {
+	Bounds        []time.Duration
+	Percentile    float64
+	Target        time.Duration
+	MinimumVolume int
+}
+
+// GetLatency returns the upper bound of the histogram bin holding percentile p within window duration
+// it is zero without calls and math.MaxInt64 when the percentile falls above the largest bound
+func (c *circuitBreaker) GetLatency(ctx context.Context, p float64) (time.Duration, error) {
+	if !c.Active {
+		return 0, nil
+	}
+
+	bounds := c.getLatencyBounds()
+	counts, total, err := c.getLatencyHistogram(ctx)
+	if err != nil || total == 0 {
+		return 0, err
+	}
+
+	rank := int64(math.Ceil(p * float64(total)))
+	cumulative := int64(0)
+	for i, bound := range bounds {
+		cumulative += counts[i]
+		if cumulative >= rank {
+			return bound, nil
+		}
+	}
+
+	return time.Duration(math.MaxInt64), nil
+}
+
+// IsExceedingLatency tells whether less than p of the calls within window duration finished within target
+// a bin is counted within target only when its upper bound is, so target should be one of the bounds
+func (c *circuitBreaker) IsExceedingLatency(ctx context.Context, p float64, target time.Duration) (bool, error) {
+	if !c.Active {
+		return false, nil
+	}
+
+	_, isExceeding, err := c.isExceedingLatency(ctx, p, target)
+	return isExceeding, err
+}
+
+// isExceedingLatency also returns the number of calls, so callers can apply a minimum volume
+func (c *circuitBreaker) isExceedingLatency(ctx context.Context, p float64, target time.Duration) (int, bool, error) {
+	counts, total, err := c.getLatencyHistogram(ctx)
+	if err != nil || total == 0 {
+		return 0, false, err
+	}
+
+	within := int64(0)
+	for i, bound := range c.getLatencyBounds() {
+		if bound > target {
+			break
+		}
+		within += counts[i]
+	}
+
+	return int(total), float64(within) < p*float64(total), nil
+}
+
+// RecordLatency adds latency to the histogram of the latest buckets
+// it opens the circuit when LatencyConfig percentile goes above target
+func (c *circuitBreaker) RecordLatency(ctx context.Context, latency time.Duration) error {
+	if !c.Active {
+		return nil
+	}
+
+	if err := c.getSeries(c.getLatencySeries(latency)).incrementLatestBuckets(ctx, 1); err != nil {
+		return err
+	}
+
+	config := c.LatencyConfig
+	if config.Percentile <= 0 || config.Target <= 0 {
+		return nil
+	}
+
+	total, isExceeding, err := c.isExceedingLatency(ctx, config.Percentile, config.Target)
+	if err != nil {
+		return err
+	}
+	if !isExceeding || total < config.MinimumVolume {
+		return nil
+	}
+
+	return c.openIfClosed(ctx)
+}
+
+// SetLatencyConfig sets histogram bounds and latency trip configuration
+func (c *circuitBreaker) SetLatencyConfig(config LatencyConfig) {
+	c.LatencyConfig = config
+}
+
+// getLatencyBounds returns LatencyConfig bounds or DefaultLatencyBounds
+func (c *circuitBreaker) getLatencyBounds() []time.Duration {
+	if len(c.LatencyConfig.Bounds) == 0 {
+		return DefaultLatencyBounds
+	}
+
+	return c.LatencyConfig.Bounds
+}
+
+// getLatencyHistogram merges the histogram of every key within window duration
+// counts has one entry per bound plus the overflow bin
+func (c *circuitBreaker) getLatencyHistogram(ctx context.Context) ([]int64, int64, error) {
+	bounds := c.getLatencyBounds()
+
+	names := make([]string, 0, len(bounds)+1)
+	for _, bound := range bounds {
+		names = append(names, latencySeriesName(durationName(bound)))
+	}
+	names = append(names, latencySeriesName(latencyOverflow))
+
+	counts, err := c.calculateSeriesValues(ctx, names)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := int64(0)
+	for _, count := range counts {
+		total = saturatingAdd(total, count)
+	}
+
+	return counts, total, nil
+}
+
+// getLatencySeries returns the series of the smallest bound holding latency
+// example: latency_100ms
+func (c *circuitBreaker) getLatencySeries(latency time.Duration) string {
+	for _, bound := range c.getLatencyBounds() {
+		if latency <= bound {
+			return latencySeriesName(durationName(bound))
+		}
+	}
+
+	return latencySeriesName(latencyOverflow)
+}
+
+func latencySeriesName(bin string) string {
+	return fmt.Sprintf("latency_%s", bin)
+}
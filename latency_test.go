@@ -0,0 +1,184 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+)
+
+func TestCircuitBreaker_Latency(t *testing.T) {
+	type Request struct {
+		latencies map[time.Duration]int
+		p         float64
+		target    time.Duration
+	}
+
+	type Response struct {
+		latency     time.Duration
+		isExceeding bool
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"p99 within target": {
+			request: Request{
+				latencies: map[time.Duration]int{8 * time.Millisecond: 99, 1500 * time.Millisecond: 1},
+				p:         0.99,
+				target:    10 * time.Millisecond,
+			},
+			response: Response{
+				latency:     10 * time.Millisecond,
+				isExceeding: false,
+			},
+		},
+		"p99 above target": {
+			request: Request{
+				latencies: map[time.Duration]int{8 * time.Millisecond: 95, 1500 * time.Millisecond: 5},
+				p:         0.99,
+				target:    10 * time.Millisecond,
+			},
+			response: Response{
+				latency:     2 * time.Second,
+				isExceeding: true,
+			},
+		},
+		"p50 of mixed latencies": {
+			request: Request{
+				latencies: map[time.Duration]int{time.Millisecond: 40, 150 * time.Millisecond: 40, 3 * time.Second: 20},
+				p:         0.5,
+				target:    200 * time.Millisecond,
+			},
+			response: Response{
+				latency:     200 * time.Millisecond,
+				isExceeding: false,
+			},
+		},
+		"Above the largest bound": {
+			request: Request{
+				latencies: map[time.Duration]int{time.Millisecond: 90, time.Minute: 10},
+				p:         0.95,
+				target:    10 * time.Second,
+			},
+			response: Response{
+				latency:     time.Duration(math.MaxInt64),
+				isExceeding: true,
+			},
+		},
+		"No calls": {
+			request: Request{
+				p:      0.99,
+				target: 10 * time.Millisecond,
+			},
+			response: Response{
+				latency:     0,
+				isExceeding: false,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, client := newRedisClient(t)
+			cb, _ := newTestCircuitBreaker(
+				t,
+				client,
+				"test",
+				circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Minute)),
+				circuitbreaker.WithLatency(circuitbreaker.LatencyConfig{}),
+				circuitbreaker.WithWindow(5*time.Minute),
+			)
+
+			for latency, count := range tc.request.latencies {
+				for i := 0; i < count; i++ {
+					assert.Nil(t, cb.RecordLatency(ctx, latency))
+				}
+			}
+
+			latency, err := cb.GetLatency(ctx, tc.request.p)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.latency, latency)
+
+			isExceeding, err := cb.IsExceedingLatency(ctx, tc.request.p, tc.request.target)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.isExceeding, isExceeding)
+
+			state, err := cb.GetState(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, circuitbreaker.StateClosed, state)
+		})
+	}
+}
+
+func TestCircuitBreaker_LatencyTrip(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	cb, _ := newTestCircuitBreaker(
+		t,
+		client,
+		"test",
+		circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Minute)),
+		circuitbreaker.WithLatency(circuitbreaker.LatencyConfig{
+			Bounds:        []time.Duration{10 * time.Millisecond, 100 * time.Millisecond, time.Second},
+			Percentile:    0.99,
+			Target:        100 * time.Millisecond,
+			MinimumVolume: 10,
+		}),
+		circuitbreaker.WithWindow(5*time.Minute),
+	)
+
+	for i := 0; i < 9; i++ {
+		assert.Nil(t, cb.RecordLatency(ctx, 500*time.Millisecond))
+	}
+	state, err := cb.GetState(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.StateClosed, state)
+
+	assert.Nil(t, cb.RecordLatency(ctx, 500*time.Millisecond))
+	state, err = cb.GetState(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.StateOpen, state)
+
+	latency, err := cb.GetLatency(ctx, 0.99)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second, latency)
+}
+
+func TestCircuitBreaker_LatencyWindow(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	cb, clock := newTestCircuitBreaker(
+		t,
+		client,
+		"test",
+		circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Minute)),
+		circuitbreaker.WithLatency(circuitbreaker.LatencyConfig{}),
+		circuitbreaker.WithWindow(5*time.Minute),
+	)
+
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, cb.RecordLatency(ctx, 3*time.Second))
+	}
+	clock.Advance(2 * time.Minute)
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, cb.RecordLatency(ctx, 3*time.Millisecond))
+	}
+
+	// both minutes are merged while they are inside the window
+	latency, err := cb.GetLatency(ctx, 0.9)
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Second, latency)
+
+	// slow minute leaves the window
+	clock.Advance(4 * time.Minute)
+	latency, err = cb.GetLatency(ctx, 0.9)
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Millisecond, latency)
+}
@@ -0,0 +1,201 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	ErrUnknownLevel = errors.New("unknown threshold level")
+)
+
+const (
+	LevelNameWarning = "warning"
+	LevelNameTrip    = "trip"
+)
+
+type LevelAction int
+
+const (
+	// ActionLatch sets the latch key of the level until it expires
+	ActionLatch LevelAction = iota
+	// ActionTrip opens the circuit, the circuit state is the latch of the level
+	ActionTrip
+)
+
+// ThresholdLevel is a named threshold of the window value
+// Percentage, when set, replaces Threshold with a percentage of the current threshold so the level follows LimitProvider
+// Expiration is how long the latch key lives and defaults to CacheTTL, OnReached is called once the level is latched
+type ThresholdLevel struct {
+	Name       string
+	Threshold  int64
+	Percentage float64
+	Expiration time.Duration
+	Action     LevelAction
+	OnReached  func(ctx context.Context, windowValue int64)
+}
+
+// GetLevelLatch retrieves whether the level has been reached and is still latched
+func (c *circuitBreaker) GetLevelLatch(ctx context.Context, name string) (bool, error) {
+	levels, err := c.getThresholdLevels(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, level := range levels {
+		if level.Name != name {
+			continue
+		}
+		if level.Action == ActionTrip {
+			return c.GetTrip(ctx)
+		}
+		return c.getBoolCache(ctx, c.getLevelKey(level))
+	}
+
+	return false, fmt.Errorf("%w: %q", ErrUnknownLevel, name)
+}
+
+// Level returns the name of the highest level current window value + amount reaches, empty when none is reached
+func (c *circuitBreaker) Level(ctx context.Context, amount int64) (string, error) {
+	if !c.Active {
+		return "", nil
+	}
+
+	windowValue, err := c.CalculateWindowValue(ctx)
+	if err != nil {
+		return "", err
+	}
+	levels, err := c.getThresholdLevels(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	name := ""
+	value := saturatingAdd(windowValue, amount)
+	for _, level := range levels {
+		if value >= level.Threshold {
+			name = level.Name
+		}
+	}
+
+	return name, nil
+}
+
+// SetThresholdLevels replaces the default warning and trip levels, levels are sorted by threshold
+// levels are checked the same way New checks WithThresholdLevels, invalid levels are rejected with ConfigError
+func (c *circuitBreaker) SetThresholdLevels(levels []ThresholdLevel) error {
+	if err := validateThresholdLevels(levels); err != nil {
+		return err
+	}
+	c.setThresholdLevels(levels)
+
+	return nil
+}
+
+// setThresholdLevels replaces the levels without checking them, New checks them in validate
+func (c *circuitBreaker) setThresholdLevels(levels []ThresholdLevel) {
+	c.ThresholdLevels = append([]ThresholdLevel{}, levels...)
+	sort.SliceStable(c.ThresholdLevels, func(i, j int) bool {
+		return c.ThresholdLevels[i].Threshold < c.ThresholdLevels[j].Threshold
+	})
+}
+
+// getThresholdLevels returns ThresholdLevels or the levels made of the warning threshold and threshold
+// percentage levels are resolved against the current threshold and every level is sorted by threshold
+func (c *circuitBreaker) getThresholdLevels(ctx context.Context) ([]ThresholdLevel, error) {
+	if len(c.ThresholdLevels) == 0 {
+		threshold, err := c.getThreshold(ctx)
+		if err != nil {
+			return nil, err
+		}
+		warningThreshold, err := c.getWarningThreshold(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		levels := []ThresholdLevel{}
+		if warningThreshold > 0 {
+			levels = append(levels, ThresholdLevel{
+				Name:       LevelNameWarning,
+				Threshold:  warningThreshold,
+				Expiration: WarningAlertKeyExpiration,
+				Action:     ActionLatch,
+			})
+		}
+
+		return append(levels, ThresholdLevel{
+			Name:      LevelNameTrip,
+			Threshold: threshold,
+			Action:    ActionTrip,
+		}), nil
+	}
+
+	levels := append([]ThresholdLevel{}, c.ThresholdLevels...)
+	for i, level := range levels {
+		if level.Percentage <= 0 {
+			continue
+		}
+
+		threshold, err := c.getThreshold(ctx)
+		if err != nil {
+			return nil, err
+		}
+		levels[i].Threshold = getPercentage(threshold, level.Percentage)
+	}
+	sort.SliceStable(levels, func(i, j int) bool {
+		return levels[i].Threshold < levels[j].Threshold
+	})
+
+	return levels, nil
+}
+
+// latchLevels latches every level windowValue has reached and reports what has changed
+func (c *circuitBreaker) latchLevels(ctx context.Context, windowValue int64, transitions *Transitions) error {
+	levels, err := c.getThresholdLevels(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, level := range levels {
+		if windowValue < level.Threshold {
+			break
+		}
+
+		isLatched, err := c.GetLevelLatch(ctx, level.Name)
+		if err != nil && !errors.Is(err, ErrCacheMiss) {
+			return err
+		}
+		if isLatched {
+			continue
+		}
+
+		if level.Action == ActionTrip {
+			if err := c.setState(ctx, StateOpen, c.Clock.Now().UTC()); err != nil {
+				return err
+			}
+			transitions.Tripped = true
+		} else {
+			if err := c.updateBoolCache(ctx, true, c.getLevelKey(level), level.Expiration); err != nil {
+				return err
+			}
+			transitions.Warned = true
+		}
+		transitions.Levels = append(transitions.Levels, level.Name)
+
+		if level.OnReached != nil {
+			level.OnReached(ctx, windowValue)
+		}
+	}
+
+	return nil
+}
+
+// getLevelKey with format <key_prefix>-<level_name>_alert-<feature_name>-<window_duration_string>
+// the warning level key is WarningAlertKey
+// example: cb-page_alert-loan_disbursement-24h
+func (c *circuitBreaker) getLevelKey(level ThresholdLevel) string {
+	return fmt.Sprintf("%s-%s_alert-%s-%s", c.KeyPrefix, level.Name, c.FeatureName, c.WindowDurationStr)
+}
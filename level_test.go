@@ -0,0 +1,223 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+)
+
+func TestCircuitBreaker_Level(t *testing.T) {
+	type Request struct {
+		levels  []circuitbreaker.ThresholdLevel
+		records []int64
+		amount  int64
+	}
+
+	type Response struct {
+		level string
+	}
+
+	tiers := []circuitbreaker.ThresholdLevel{
+		{Name: "block", Threshold: 100, Action: circuitbreaker.ActionTrip},
+		{Name: "info", Threshold: 50},
+		{Name: "page", Threshold: 90},
+		{Name: "warn", Threshold: 75},
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Below every level": {
+			request: Request{
+				levels:  tiers,
+				records: []int64{20},
+				amount:  10,
+			},
+			response: Response{
+				level: "",
+			},
+		},
+		"Amount reaches info": {
+			request: Request{
+				levels:  tiers,
+				records: []int64{40},
+				amount:  10,
+			},
+			response: Response{
+				level: "info",
+			},
+		},
+		"Highest reached level wins": {
+			request: Request{
+				levels:  tiers,
+				records: []int64{40, 40},
+				amount:  10,
+			},
+			response: Response{
+				level: "page",
+			},
+		},
+		"Block": {
+			request: Request{
+				levels:  tiers,
+				records: []int64{100},
+			},
+			response: Response{
+				level: "block",
+			},
+		},
+		"Default warning level": {
+			request: Request{
+				records: []int64{60},
+			},
+			response: Response{
+				level: circuitbreaker.LevelNameWarning,
+			},
+		},
+		"Default trip level": {
+			request: Request{
+				records: []int64{60},
+				amount:  40,
+			},
+			response: Response{
+				level: circuitbreaker.LevelNameTrip,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, client := newRedisClient(t)
+			cb, _ := newTestCircuitBreaker(t, client, "test", circuitbreaker.WithThreshold(100))
+			cb.SetWarningThreshold(50)
+			if tc.request.levels != nil {
+				assert.Nil(t, cb.SetThresholdLevels(tc.request.levels))
+			}
+
+			for _, amount := range tc.request.records {
+				assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, amount))
+			}
+
+			result, err := cb.Level(ctx, tc.request.amount)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.level, result)
+		})
+	}
+}
+
+func TestCircuitBreaker_SetThresholdLevelsInvalid(t *testing.T) {
+	testcases := map[string][]circuitbreaker.ThresholdLevel{
+		"Empty name": {
+			{Threshold: 50},
+		},
+		"Name containing dash": {
+			{Name: "on-call", Threshold: 50},
+		},
+		"Duplicated name": {
+			{Name: "page", Threshold: 50},
+			{Name: "page", Threshold: 90},
+		},
+		"Neither threshold nor percentage": {
+			{Name: "page"},
+		},
+	}
+
+	for name, levels := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, client := newRedisClient(t)
+			cb, _ := newTestCircuitBreaker(t, client, "test", circuitbreaker.WithThreshold(100))
+			assert.Nil(t, cb.SetThresholdLevels([]circuitbreaker.ThresholdLevel{{Name: "info", Threshold: 50}}))
+
+			err := cb.SetThresholdLevels(levels)
+			assert.ErrorIs(t, err, circuitbreaker.ErrInvalidConfig)
+
+			// the previous levels are kept
+			assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, 60))
+			level, err := cb.Level(ctx, 0)
+			assert.Nil(t, err)
+			assert.Equal(t, "info", level)
+		})
+	}
+}
+
+func TestCircuitBreaker_LevelLatch(t *testing.T) {
+	ctx := context.Background()
+	server, client := newRedisClient(t)
+
+	reached := map[string]int{}
+	onReached := func(name string) func(ctx context.Context, windowValue int64) {
+		return func(ctx context.Context, windowValue int64) {
+			reached[name]++
+		}
+	}
+
+	cb, _ := newTestCircuitBreaker(
+		t,
+		client,
+		"test",
+		circuitbreaker.WithThresholdLevels(
+			circuitbreaker.ThresholdLevel{Name: "info", Threshold: 50, Expiration: time.Hour, OnReached: onReached("info")},
+			circuitbreaker.ThresholdLevel{Name: "warn", Threshold: 75, Expiration: 6 * time.Hour, OnReached: onReached("warn")},
+			circuitbreaker.ThresholdLevel{Name: "page", Threshold: 90, OnReached: onReached("page")},
+			circuitbreaker.ThresholdLevel{Name: "block", Threshold: 100, Action: circuitbreaker.ActionTrip, OnReached: onReached("block")},
+		),
+	)
+	cb.SetAutoTrip(true)
+
+	transitions, err := cb.Record(ctx, 80)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.Transitions{WindowValue: 80, Warned: true, Levels: []string{"info", "warn"}}, transitions)
+
+	transitions, err = cb.Record(ctx, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.Transitions{WindowValue: 85}, transitions)
+
+	transitions, err = cb.Record(ctx, 20)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.Transitions{WindowValue: 105, Tripped: true, Warned: true, Levels: []string{"page", "block"}}, transitions)
+
+	assert.Equal(t, map[string]int{"info": 1, "warn": 1, "page": 1, "block": 1}, reached)
+	assert.Equal(t, time.Hour, server.TTL("cb-info_alert-test-24h"))
+	assert.Equal(t, 6*time.Hour, server.TTL("cb-warn_alert-test-24h"))
+	assert.Equal(t, 28*time.Hour, server.TTL("cb-page_alert-test-24h"))
+
+	for _, name := range []string{"info", "warn", "page", "block"} {
+		isLatched, err := cb.GetLevelLatch(ctx, name)
+		assert.Nil(t, err)
+		assert.True(t, isLatched, name)
+	}
+
+	_, err = cb.GetLevelLatch(ctx, "warning")
+	assert.ErrorIs(t, err, circuitbreaker.ErrUnknownLevel)
+}
+
+func TestCircuitBreaker_WarningLevelExpiration(t *testing.T) {
+	ctx := context.Background()
+	server, client := newRedisClient(t)
+
+	cb := circuitbreaker.NewCircuitBreaker(
+		[]*circuitbreaker.Bucket{circuitbreaker.NewBucket(time.Minute)},
+		circuitbreaker.NewRedisCache(client, time.Hour),
+		28*time.Hour,
+		"test",
+		time.Hour,
+	)
+	cb.SetThreshold(100)
+	cb.SetWarningThreshold(50)
+	cb.SetAutoTrip(true)
+
+	_, err := cb.Record(ctx, 60)
+	assert.Nil(t, err)
+
+	isLatched, err := cb.GetLevelLatch(ctx, circuitbreaker.LevelNameWarning)
+	assert.Nil(t, err)
+	assert.True(t, isLatched)
+	assert.Equal(t, circuitbreaker.WarningAlertKeyExpiration, server.TTL("cb-warning_alert-test-1h"))
+}
@@ -0,0 +1,120 @@
+package circuitbreaker
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// LimitProvider is asked for the current threshold, e.g. today's disbursement limit of a partner
+type LimitProvider interface {
+	GetLimit(ctx context.Context) (int64, error)
+}
+
+// LimitProviderFunc adapts a function to LimitProvider
+type LimitProviderFunc func(ctx context.Context) (int64, error)
+
+func (f LimitProviderFunc) GetLimit(ctx context.Context) (int64, error) {
+	return f(ctx)
+}
+
+type cachedLimitProvider struct {
+	Clock           Clock
+	Provider        LimitProvider
+	RefreshInterval time.Duration
+
+	limit      int64
+	fetchedAt  time.Time
+	fetched    bool
+	refreshing bool
+	mutex      sync.Mutex
+}
+
+// NewCachedLimitProvider remembers the limit of provider for refreshInterval
+// when a refresh fails the last limit keeps being served and the next refresh waits another refreshInterval,
+// clock defaults to the real clock when nil
+func NewCachedLimitProvider(provider LimitProvider, refreshInterval time.Duration, clock Clock) LimitProvider {
+	if clock == nil {
+		clock = NewRealClock()
+	}
+
+	return &cachedLimitProvider{
+		Clock:           clock,
+		Provider:        provider,
+		RefreshInterval: refreshInterval,
+	}
+}
+
+// GetLimit asks provider without holding the lock, while one caller refreshes the others are served the last limit
+// until a limit is known every caller asks provider itself
+func (p *cachedLimitProvider) GetLimit(ctx context.Context) (int64, error) {
+	p.mutex.Lock()
+	now := p.Clock.Now()
+	if p.fetched && (p.refreshing || now.Sub(p.fetchedAt) < p.RefreshInterval) {
+		limit := p.limit
+		p.mutex.Unlock()
+		return limit, nil
+	}
+	p.refreshing = p.fetched
+	p.mutex.Unlock()
+
+	limit, err := p.Provider.GetLimit(ctx)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.refreshing = false
+	if err != nil {
+		if !p.fetched {
+			return 0, err
+		}
+		// back off, the provider is not asked again before another refresh interval
+		p.fetchedAt = now
+		return p.limit, nil
+	}
+
+	p.limit = limit
+	p.fetchedAt = now
+	p.fetched = true
+
+	return limit, nil
+}
+
+// SetLimitProvider makes the threshold follow provider instead of Threshold
+func (c *circuitBreaker) SetLimitProvider(provider LimitProvider) {
+	c.LimitProvider = provider
+}
+
+// SetWarningPercentage makes the warning threshold a percentage of the threshold instead of WarningThreshold
+func (c *circuitBreaker) SetWarningPercentage(percentage float64) {
+	c.WarningPercentage = percentage
+}
+
+// getThreshold returns the limit of LimitProvider or Threshold
+func (c *circuitBreaker) getThreshold(ctx context.Context) (int64, error) {
+	if c.LimitProvider == nil {
+		return c.Threshold, nil
+	}
+
+	return c.LimitProvider.GetLimit(ctx)
+}
+
+// getWarningThreshold returns WarningPercentage of the threshold or WarningThreshold
+func (c *circuitBreaker) getWarningThreshold(ctx context.Context) (int64, error) {
+	if c.WarningPercentage <= 0 {
+		return c.WarningThreshold, nil
+	}
+
+	threshold, err := c.getThreshold(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return getPercentage(threshold, c.WarningPercentage), nil
+}
+
+// getPercentage returns percentage of limit rounded up, so the level is never reached earlier than asked
+func getPercentage(limit int64, percentage float64) int64 {
+	return saturatingFloat(math.Ceil(float64(limit) * percentage / 100))
+}
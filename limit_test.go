@@ -0,0 +1,252 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/cbtest"
+)
+
+var ErrLimitUnavailable = errors.New("limit unavailable")
+
+func TestLimit_CachedLimitProvider(t *testing.T) {
+	ctx := context.Background()
+	clock := cbtest.NewClock(time.Date(2023, time.May, 12, 10, 0, 0, 0, time.UTC))
+
+	calls, failures := 0, 0
+	limits := []int64{100, 200}
+	var providerErr error
+	provider := circuitbreaker.NewCachedLimitProvider(circuitbreaker.LimitProviderFunc(func(ctx context.Context) (int64, error) {
+		if providerErr != nil {
+			failures++
+			return 0, providerErr
+		}
+		calls++
+		return limits[calls-1], nil
+	}), time.Minute, clock)
+
+	limit, err := provider.GetLimit(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), limit)
+
+	clock.Advance(30 * time.Second)
+	limit, err = provider.GetLimit(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), limit)
+	assert.Equal(t, 1, calls)
+
+	clock.Advance(30 * time.Second)
+	limit, err = provider.GetLimit(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(200), limit)
+	assert.Equal(t, 2, calls)
+
+	// failed refresh keeps serving the last limit
+	providerErr = ErrLimitUnavailable
+	clock.Advance(time.Minute)
+	limit, err = provider.GetLimit(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(200), limit)
+	assert.Equal(t, 1, failures)
+
+	// and the provider is not asked again before another refresh interval
+	clock.Advance(30 * time.Second)
+	limit, err = provider.GetLimit(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(200), limit)
+	assert.Equal(t, 1, failures)
+
+	clock.Advance(30 * time.Second)
+	_, err = provider.GetLimit(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, failures)
+}
+
+func TestLimit_CachedLimitProviderRefreshing(t *testing.T) {
+	ctx := context.Background()
+	clock := cbtest.NewClock(time.Date(2023, time.May, 12, 10, 0, 0, 0, time.UTC))
+
+	limit := int64(100)
+	refreshing := make(chan struct{})
+	unblock := make(chan struct{})
+	provider := circuitbreaker.NewCachedLimitProvider(circuitbreaker.LimitProviderFunc(func(ctx context.Context) (int64, error) {
+		if limit == 200 {
+			close(refreshing)
+			<-unblock
+		}
+		return limit, nil
+	}), time.Minute, clock)
+
+	_, err := provider.GetLimit(ctx)
+	assert.Nil(t, err)
+
+	limit = 200
+	clock.Advance(time.Minute)
+	done := make(chan int64)
+	go func() {
+		refreshed, _ := provider.GetLimit(ctx)
+		done <- refreshed
+	}()
+	<-refreshing
+
+	// a slow refresh doesn't block the other callers, they keep the last limit
+	stale, err := provider.GetLimit(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), stale)
+
+	close(unblock)
+	assert.Equal(t, int64(200), <-done)
+}
+
+func TestLimit_CachedLimitProviderWithoutLimit(t *testing.T) {
+	provider := circuitbreaker.NewCachedLimitProvider(circuitbreaker.LimitProviderFunc(func(ctx context.Context) (int64, error) {
+		return 0, ErrLimitUnavailable
+	}), time.Minute, nil)
+
+	_, err := provider.GetLimit(context.Background())
+	assert.ErrorIs(t, err, ErrLimitUnavailable)
+}
+
+func TestCircuitBreaker_LimitProvider(t *testing.T) {
+	type Request struct {
+		limit             int64
+		warningPercentage float64
+		amount            int64
+	}
+
+	type Response struct {
+		isExceeding        bool
+		isExceedingWarning bool
+		level              string
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Within limit and warning percentage": {
+			request: Request{
+				limit:             200,
+				warningPercentage: 80,
+				amount:            50,
+			},
+			response: Response{
+				level: "",
+			},
+		},
+		"Warning percentage follows limit": {
+			request: Request{
+				limit:             100,
+				warningPercentage: 80,
+				amount:            20,
+			},
+			response: Response{
+				isExceedingWarning: true,
+				level:              circuitbreaker.LevelNameWarning,
+			},
+		},
+		"Limit lowered below window value": {
+			request: Request{
+				limit:             50,
+				warningPercentage: 80,
+				amount:            1,
+			},
+			response: Response{
+				isExceeding:        true,
+				isExceedingWarning: true,
+				level:              circuitbreaker.LevelNameTrip,
+			},
+		},
+		"Warning percentage rounds up": {
+			request: Request{
+				limit:             99,
+				warningPercentage: 50,
+				amount:            -11,
+			},
+			response: Response{
+				level: "",
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, client := newRedisClient(t)
+			cb, _ := newTestCircuitBreaker(t, client, "test")
+			cb.SetLimitProvider(circuitbreaker.LimitProviderFunc(func(ctx context.Context) (int64, error) {
+				return tc.request.limit, nil
+			}))
+			cb.SetWarningPercentage(tc.request.warningPercentage)
+			assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, 60))
+
+			isExceeding, err := cb.IsExceedingThreshold(ctx, tc.request.amount)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.isExceeding, isExceeding)
+
+			isExceedingWarning, err := cb.IsExceedingWarningThreshold(ctx, tc.request.amount)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.isExceedingWarning, isExceedingWarning)
+
+			level, err := cb.Level(ctx, tc.request.amount)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.level, level)
+		})
+	}
+}
+
+func TestCircuitBreaker_LimitProviderPercentageLevels(t *testing.T) {
+	ctx := context.Background()
+	limit := int64(100)
+	_, client := newRedisClient(t)
+	cb, _ := newTestCircuitBreaker(t, client, "test")
+	cb.SetLimitProvider(circuitbreaker.LimitProviderFunc(func(ctx context.Context) (int64, error) {
+		return limit, nil
+	}))
+	assert.Nil(t, cb.SetThresholdLevels([]circuitbreaker.ThresholdLevel{
+		{Name: "info", Percentage: 50},
+		{Name: "page", Percentage: 90},
+		{Name: "block", Percentage: 100, Action: circuitbreaker.ActionTrip},
+	}))
+	assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, 60))
+
+	level, err := cb.Level(ctx, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "info", level)
+
+	limit = 60
+	level, err = cb.Level(ctx, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "block", level)
+
+	allowed, _, err := cb.TryConsume(ctx, 1)
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+}
+
+func TestCircuitBreaker_LimitProviderError(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	cb, _ := newTestCircuitBreaker(t, client, "test")
+	cb.SetLimitProvider(circuitbreaker.LimitProviderFunc(func(ctx context.Context) (int64, error) {
+		return 0, ErrLimitUnavailable
+	}))
+	cb.SetWarningPercentage(80)
+
+	_, err := cb.IsExceedingThreshold(ctx, 1)
+	assert.ErrorIs(t, err, ErrLimitUnavailable)
+
+	_, err = cb.IsExceedingWarningThreshold(ctx, 1)
+	assert.ErrorIs(t, err, ErrLimitUnavailable)
+
+	_, _, err = cb.TryConsume(ctx, 1)
+	assert.ErrorIs(t, err, ErrLimitUnavailable)
+
+	_, err = cb.Reserve(ctx, 1)
+	assert.ErrorIs(t, err, ErrLimitUnavailable)
+}
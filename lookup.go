@@ -0,0 +1,66 @@
+package circuitbreaker
+
+import "time"
+
+type LookupStrategy int
+
+const (
+	// LookupHead sums the whole current largest bucket as head, then walks back from the largest to the smallest bucket
+	LookupHead LookupStrategy = iota
+	// LookupExact decomposes both ends of the window into the finest buckets, the sum equals the sliding window total
+	// at the precision of the smallest bucket
+	LookupExact
+	// LookupSliding keeps only the current and previous fixed windows and weights the previous one by the part
+	// of it still inside the sliding window, every lookup reads 2 keys and every update writes 1 key
+	LookupSliding
+)
+
+// slidingBucketName names the fixed window keys of LookupSliding so they never collide with bucket keys
+const slidingBucketName = "sliding"
+
+// SetLookupStrategy sets how GenerateKeys covers the window
+func (c *circuitBreaker) SetLookupStrategy(strategy LookupStrategy) {
+	c.LookupStrategy = strategy
+}
+
+// generateExactKeys covers the window ending with the current smallest bucket with aligned buckets
+// every step takes the largest bucket that starts at the cursor and still ends inside the window
+func (c *circuitBreaker) generateExactKeys(currentTime time.Time) []string {
+	result := []string{}
+
+	smallest := c.Buckets[len(c.Buckets)-1].Duration
+	endTime := currentTime.Truncate(smallest).Add(smallest)
+	cursor := endTime.Add(-1 * c.WindowDuration).Truncate(smallest)
+
+	for cursor.Before(endTime) {
+		found := false
+		for _, bucket := range c.Buckets {
+			next := cursor.Add(bucket.Duration)
+			if !cursor.Truncate(bucket.Duration).Equal(cursor) || next.After(endTime) {
+				continue
+			}
+
+			result = append(result, c.getTimePointKey(bucket.Name, cursor))
+			cursor = next
+			found = true
+			break
+		}
+
+		// buckets that are not multiples of the smallest bucket can leave the cursor unaligned
+		if !found {
+			break
+		}
+	}
+
+	return result
+}
+
+// getSlidingKeys returns the current and previous fixed window keys and how far currentTime is into the current one
+func (c *circuitBreaker) getSlidingKeys(currentTime time.Time) (string, string, time.Duration) {
+	currentStart := currentTime.Truncate(c.WindowDuration)
+	previousStart := currentStart.Add(-1 * c.WindowDuration)
+
+	return c.getTimePointKey(slidingBucketName, currentStart),
+		c.getTimePointKey(slidingBucketName, previousStart),
+		currentTime.Sub(currentStart)
+}
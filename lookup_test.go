@@ -0,0 +1,331 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/cbtest"
+	"go-circuit-breaker/fixture"
+)
+
+func TestLookup_GenerateExactKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mocks := fixture.NewCircuitBreakerMock(ctrl)
+	cb := circuitbreaker.NewCircuitBreaker(
+		[]*circuitbreaker.Bucket{
+			circuitbreaker.NewBucket(time.Hour),
+			circuitbreaker.NewBucket(5 * time.Minute),
+			circuitbreaker.NewBucket(time.Minute),
+		},
+		mocks.Cache,
+		24*time.Hour,
+		"test",
+		2*time.Hour,
+		circuitbreaker.WithLookupStrategy(circuitbreaker.LookupExact),
+	)
+
+	result := cb.GenerateKeys(time.Date(2023, time.May, 12, 10, 12, 30, 0, time.UTC))
+	assert.Equal(t, []string{
+		"cb-test-2h-1m-20230512081300",
+		"cb-test-2h-1m-20230512081400",
+		"cb-test-2h-5m-20230512081500",
+		"cb-test-2h-5m-20230512082000",
+		"cb-test-2h-5m-20230512082500",
+		"cb-test-2h-5m-20230512083000",
+		"cb-test-2h-5m-20230512083500",
+		"cb-test-2h-5m-20230512084000",
+		"cb-test-2h-5m-20230512084500",
+		"cb-test-2h-5m-20230512085000",
+		"cb-test-2h-5m-20230512085500",
+		"cb-test-2h-1h-20230512090000",
+		"cb-test-2h-5m-20230512100000",
+		"cb-test-2h-5m-20230512100500",
+		"cb-test-2h-1m-20230512101000",
+		"cb-test-2h-1m-20230512101100",
+		"cb-test-2h-1m-20230512101200",
+	}, result)
+}
+
+// TestLookup_ExactMatchesBruteForce records random amounts at random times and compares
+// CalculateWindowValue against the sum of every smallest bucket slot inside the window
+func TestLookup_ExactMatchesBruteForce(t *testing.T) {
+	testcases := map[string]struct {
+		buckets []*circuitbreaker.Bucket
+		window  time.Duration
+	}{
+		"Default buckets one day window": {
+			buckets: circuitbreaker.DefaultBucket,
+			window:  24 * time.Hour,
+		},
+		"Planned buckets six hour window": {
+			buckets: circuitbreaker.PlanBuckets(6*time.Hour, 20, time.Minute),
+			window:  6 * time.Hour,
+		},
+		"Mixed window with minute buckets": {
+			buckets: []*circuitbreaker.Bucket{
+				circuitbreaker.NewBucket(time.Hour),
+				circuitbreaker.NewBucket(15 * time.Minute),
+				circuitbreaker.NewBucket(time.Minute),
+			},
+			window: 90 * time.Minute,
+		},
+		"Second buckets five minute window": {
+			buckets: []*circuitbreaker.Bucket{
+				circuitbreaker.NewBucket(time.Minute),
+				circuitbreaker.NewBucket(10 * time.Second),
+				circuitbreaker.NewBucket(time.Second),
+			},
+			window: 5 * time.Minute,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, client := newRedisClient(t)
+			clock := cbtest.NewClock(time.Date(2023, time.May, 12, 0, 0, 0, 0, time.UTC))
+
+			cb, err := circuitbreaker.New(
+				"test",
+				circuitbreaker.WithBuckets(tc.buckets...),
+				circuitbreaker.WithCache(circuitbreaker.NewRedisCache(client, time.Hour)),
+				circuitbreaker.WithCacheTTL(2*tc.window),
+				circuitbreaker.WithClock(clock),
+				circuitbreaker.WithLookupStrategy(circuitbreaker.LookupExact),
+				circuitbreaker.WithWindow(tc.window),
+			)
+			assert.Nil(t, err)
+
+			smallest := tc.buckets[len(tc.buckets)-1].Duration
+			random := rand.New(rand.NewSource(42))
+			slots := map[time.Time]int64{}
+
+			for i := 0; i < 300; i++ {
+				clock.Advance(time.Duration(random.Int63n(int64(tc.window / 10))))
+
+				amount := random.Int63n(100) + 1
+				assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, amount))
+				slots[clock.Now().Truncate(smallest)] += amount
+
+				current := clock.Now().Truncate(smallest)
+				expected := int64(0)
+				for slot, value := range slots {
+					if slot.After(current.Add(-1*tc.window)) && !slot.After(current) {
+						expected += value
+					}
+				}
+
+				result, err := cb.CalculateWindowValue(ctx)
+				assert.Nil(t, err)
+				assert.Equal(t, expected, result, fmt.Sprintf("at %s", clock.Now()))
+			}
+		})
+	}
+}
+
+func TestLookup_Sliding(t *testing.T) {
+	type Request struct {
+		records map[time.Duration]int64
+		now     time.Duration
+	}
+
+	type Response struct {
+		windowValue int64
+		keys        int
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Sliding current window only": {
+			request: Request{
+				records: map[time.Duration]int64{10 * time.Minute: 30, 20 * time.Minute: 40},
+				now:     30 * time.Minute,
+			},
+			response: Response{
+				windowValue: 70,
+				keys:        1,
+			},
+		},
+		"Sliding weights previous window": {
+			request: Request{
+				records: map[time.Duration]int64{30 * time.Minute: 100, 75 * time.Minute: 40},
+				now:     75 * time.Minute,
+			},
+			response: Response{
+				windowValue: 115,
+				keys:        2,
+			},
+		},
+		"Sliding drops windows older than previous": {
+			request: Request{
+				records: map[time.Duration]int64{30 * time.Minute: 100, 90 * time.Minute: 20},
+				now:     150 * time.Minute,
+			},
+			response: Response{
+				windowValue: 10,
+				keys:        2,
+			},
+		},
+		"Sliding empty": {
+			request: Request{
+				now: 30 * time.Minute,
+			},
+			response: Response{
+				windowValue: 0,
+				keys:        0,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			server, client := newRedisClient(t)
+			start := time.Date(2023, time.May, 12, 9, 0, 0, 0, time.UTC)
+			clock := cbtest.NewClock(start)
+
+			cb, err := circuitbreaker.New(
+				"test",
+				circuitbreaker.WithCache(circuitbreaker.NewRedisCache(client, time.Hour)),
+				circuitbreaker.WithCacheTTL(2*time.Hour),
+				circuitbreaker.WithClock(clock),
+				circuitbreaker.WithLookupStrategy(circuitbreaker.LookupSliding),
+				circuitbreaker.WithWindow(time.Hour),
+			)
+			assert.Nil(t, err)
+
+			for _, offset := range []time.Duration{10 * time.Minute, 20 * time.Minute, 30 * time.Minute, 75 * time.Minute, 90 * time.Minute} {
+				amount, ok := tc.request.records[offset]
+				if !ok {
+					continue
+				}
+				clock.Set(start.Add(offset))
+				assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, amount))
+			}
+			clock.Set(start.Add(tc.request.now))
+
+			assert.Equal(t, 2, len(cb.GenerateKeys(clock.Now())))
+
+			result, err := cb.CalculateWindowValue(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.windowValue, result)
+			assert.Equal(t, tc.response.keys, len(server.Keys()))
+		})
+	}
+}
+
+func TestLookup_SlidingTryConsume(t *testing.T) {
+	type Request struct {
+		amount  int64
+		reserve bool
+	}
+
+	type Response struct {
+		allowed     bool
+		windowValue int64
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"TryConsume counts only the weighted previous window": {
+			request: Request{
+				amount: 20,
+			},
+			response: Response{
+				allowed:     true,
+				windowValue: 35,
+			},
+		},
+		"TryConsume reaching threshold with the weighted previous window": {
+			request: Request{
+				amount: 85,
+			},
+			response: Response{
+				allowed:     false,
+				windowValue: 15,
+			},
+		},
+		"Reserve counts only the weighted previous window": {
+			request: Request{
+				amount:  20,
+				reserve: true,
+			},
+			response: Response{
+				allowed:     true,
+				windowValue: 35,
+			},
+		},
+		"Reserve reaching threshold with the weighted previous window": {
+			request: Request{
+				amount:  85,
+				reserve: true,
+			},
+			response: Response{
+				allowed:     false,
+				windowValue: 15,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, client := newRedisClient(t)
+			start := time.Date(2023, time.May, 12, 9, 0, 0, 0, time.UTC)
+			clock := cbtest.NewClock(start.Add(30 * time.Minute))
+
+			cb, err := circuitbreaker.New(
+				"test",
+				circuitbreaker.WithCache(circuitbreaker.NewRedisCache(client, time.Hour)),
+				circuitbreaker.WithCacheTTL(2*time.Hour),
+				circuitbreaker.WithClock(clock),
+				circuitbreaker.WithLookupStrategy(circuitbreaker.LookupSliding),
+				circuitbreaker.WithThreshold(100),
+				circuitbreaker.WithWindow(time.Hour),
+			)
+			assert.Nil(t, err)
+			cb.SetReservationTimeout(0)
+
+			// 90 in the previous window, 10 minutes of it are still inside the sliding window
+			assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, 90))
+			clock.Set(start.Add(110 * time.Minute))
+			isExceeding, err := cb.IsExceedingThreshold(ctx, tc.request.amount)
+			assert.Nil(t, err)
+			assert.Equal(t, !tc.response.allowed, isExceeding)
+
+			allowed := false
+			if tc.request.reserve {
+				_, err = cb.Reserve(ctx, tc.request.amount)
+				allowed = err == nil
+				if !allowed {
+					assert.Equal(t, circuitbreaker.ErrThresholdExceeded, err)
+				}
+			} else {
+				var windowValue int64
+				allowed, windowValue, err = cb.TryConsume(ctx, tc.request.amount)
+				assert.Nil(t, err)
+				if allowed {
+					assert.Equal(t, tc.response.windowValue, windowValue)
+				}
+			}
+			assert.Equal(t, tc.response.allowed, allowed)
+
+			windowValue, err := cb.CalculateWindowValue(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.windowValue, windowValue)
+		})
+	}
+}
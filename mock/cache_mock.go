@@ -5,6 +5,7 @@
 package mock
 
 import (
+	context "context"
 	reflect "reflect"
 	time "time"
 
@@ -34,58 +35,106 @@ func (m *MockCache) EXPECT() *MockCacheMockRecorder {
 	return m.recorder
 }
 
-// Get mocks base method.
-func (m *MockCache) Get(arg0 string) (interface{}, error) {
+// GetBool mocks base method.
+func (m *MockCache) GetBool(arg0 context.Context, arg1 string) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", arg0)
-	ret0, _ := ret[0].(interface{})
+	ret := m.ctrl.Call(m, "GetBool", arg0, arg1)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Get indicates an expected call of Get.
-func (mr *MockCacheMockRecorder) Get(arg0 interface{}) *gomock.Call {
+// GetBool indicates an expected call of GetBool.
+func (mr *MockCacheMockRecorder) GetBool(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCache)(nil).Get), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBool", reflect.TypeOf((*MockCache)(nil).GetBool), arg0, arg1)
 }
 
-// GetMulti mocks base method.
-func (m *MockCache) GetMulti(arg0 []string) interface{} {
+// GetInts mocks base method.
+func (m *MockCache) GetInts(arg0 context.Context, arg1 []string) (map[string]int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetMulti", arg0)
-	ret0, _ := ret[0].(interface{})
-	return ret0
+	ret := m.ctrl.Call(m, "GetInts", arg0, arg1)
+	ret0, _ := ret[0].(map[string]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// GetMulti indicates an expected call of GetMulti.
-func (mr *MockCacheMockRecorder) GetMulti(arg0 interface{}) *gomock.Call {
+// GetInts indicates an expected call of GetInts.
+func (mr *MockCacheMockRecorder) GetInts(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMulti", reflect.TypeOf((*MockCache)(nil).GetMulti), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInts", reflect.TypeOf((*MockCache)(nil).GetInts), arg0, arg1)
+}
+
+// GetString mocks base method.
+func (m *MockCache) GetString(arg0 context.Context, arg1 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetString", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetString indicates an expected call of GetString.
+func (mr *MockCacheMockRecorder) GetString(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetString", reflect.TypeOf((*MockCache)(nil).GetString), arg0, arg1)
 }
 
 // IncrementInt mocks base method.
-func (m *MockCache) IncrementInt(arg0 string, arg1 int) (int, error) {
+func (m *MockCache) IncrementInt(arg0 context.Context, arg1 string, arg2 int64, arg3 time.Duration) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "IncrementInt", arg0, arg1)
-	ret0, _ := ret[0].(int)
+	ret := m.ctrl.Call(m, "IncrementInt", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // IncrementInt indicates an expected call of IncrementInt.
-func (mr *MockCacheMockRecorder) IncrementInt(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockCacheMockRecorder) IncrementInt(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementInt", reflect.TypeOf((*MockCache)(nil).IncrementInt), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementInt", reflect.TypeOf((*MockCache)(nil).IncrementInt), arg0, arg1, arg2, arg3)
 }
 
-// Set mocks base method.
-func (m *MockCache) Set(arg0 string, arg1 interface{}, arg2 time.Duration) {
+// IncrementIntIfBelow mocks base method.
+func (m *MockCache) IncrementIntIfBelow(arg0 context.Context, arg1, arg2 []string, arg3, arg4 int64, arg5 time.Duration) (bool, int64, error) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Set", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "IncrementIntIfBelow", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// IncrementIntIfBelow indicates an expected call of IncrementIntIfBelow.
+func (mr *MockCacheMockRecorder) IncrementIntIfBelow(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementIntIfBelow", reflect.TypeOf((*MockCache)(nil).IncrementIntIfBelow), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// SetBool mocks base method.
+func (m *MockCache) SetBool(arg0 context.Context, arg1 string, arg2 bool, arg3 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBool", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetBool indicates an expected call of SetBool.
+func (mr *MockCacheMockRecorder) SetBool(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBool", reflect.TypeOf((*MockCache)(nil).SetBool), arg0, arg1, arg2, arg3)
+}
+
+// SetString mocks base method.
+func (m *MockCache) SetString(arg0 context.Context, arg1, arg2 string, arg3 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetString", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Set indicates an expected call of Set.
-func (mr *MockCacheMockRecorder) Set(arg0, arg1, arg2 interface{}) *gomock.Call {
+// SetString indicates an expected call of SetString.
+func (mr *MockCacheMockRecorder) SetString(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockCache)(nil).Set), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetString", reflect.TypeOf((*MockCache)(nil).SetString), arg0, arg1, arg2, arg3)
 }
@@ -6,6 +6,7 @@ package mock
 
 import (
 	context "context"
+	circuitbreaker "go-circuit-breaker"
 	reflect "reflect"
 	time "time"
 
@@ -35,11 +36,26 @@ func (m *MockCircuitBreaker) EXPECT() *MockCircuitBreakerMockRecorder {
 	return m.recorder
 }
 
+// Allow mocks base method.
+func (m *MockCircuitBreaker) Allow(arg0 context.Context) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Allow", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Allow indicates an expected call of Allow.
+func (mr *MockCircuitBreakerMockRecorder) Allow(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Allow", reflect.TypeOf((*MockCircuitBreaker)(nil).Allow), arg0)
+}
+
 // CalculateWindowValue mocks base method.
-func (m *MockCircuitBreaker) CalculateWindowValue(arg0 context.Context) (int, error) {
+func (m *MockCircuitBreaker) CalculateWindowValue(arg0 context.Context) (int64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CalculateWindowValue", arg0)
-	ret0, _ := ret[0].(int)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -50,6 +66,20 @@ func (mr *MockCircuitBreakerMockRecorder) CalculateWindowValue(arg0 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CalculateWindowValue", reflect.TypeOf((*MockCircuitBreaker)(nil).CalculateWindowValue), arg0)
 }
 
+// Execute mocks base method.
+func (m *MockCircuitBreaker) Execute(arg0 context.Context, arg1 int64, arg2 func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Execute", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Execute indicates an expected call of Execute.
+func (mr *MockCircuitBreakerMockRecorder) Execute(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Execute", reflect.TypeOf((*MockCircuitBreaker)(nil).Execute), arg0, arg1, arg2)
+}
+
 // GenerateKeys mocks base method.
 func (m *MockCircuitBreaker) GenerateKeys(arg0 time.Time) []string {
 	m.ctrl.T.Helper()
@@ -64,6 +94,95 @@ func (mr *MockCircuitBreakerMockRecorder) GenerateKeys(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateKeys", reflect.TypeOf((*MockCircuitBreaker)(nil).GenerateKeys), arg0)
 }
 
+// GetActive mocks base method.
+func (m *MockCircuitBreaker) GetActive() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActive")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// GetActive indicates an expected call of GetActive.
+func (mr *MockCircuitBreakerMockRecorder) GetActive() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActive", reflect.TypeOf((*MockCircuitBreaker)(nil).GetActive))
+}
+
+// GetFailureRate mocks base method.
+func (m *MockCircuitBreaker) GetFailureRate(arg0 context.Context) (float64, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFailureRate", arg0)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetFailureRate indicates an expected call of GetFailureRate.
+func (mr *MockCircuitBreakerMockRecorder) GetFailureRate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFailureRate", reflect.TypeOf((*MockCircuitBreaker)(nil).GetFailureRate), arg0)
+}
+
+// GetFeatureName mocks base method.
+func (m *MockCircuitBreaker) GetFeatureName() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFeatureName")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetFeatureName indicates an expected call of GetFeatureName.
+func (mr *MockCircuitBreakerMockRecorder) GetFeatureName() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFeatureName", reflect.TypeOf((*MockCircuitBreaker)(nil).GetFeatureName))
+}
+
+// GetLatency mocks base method.
+func (m *MockCircuitBreaker) GetLatency(arg0 context.Context, arg1 float64) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatency", arg0, arg1)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatency indicates an expected call of GetLatency.
+func (mr *MockCircuitBreakerMockRecorder) GetLatency(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatency", reflect.TypeOf((*MockCircuitBreaker)(nil).GetLatency), arg0, arg1)
+}
+
+// GetLevelLatch mocks base method.
+func (m *MockCircuitBreaker) GetLevelLatch(arg0 context.Context, arg1 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLevelLatch", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLevelLatch indicates an expected call of GetLevelLatch.
+func (mr *MockCircuitBreakerMockRecorder) GetLevelLatch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLevelLatch", reflect.TypeOf((*MockCircuitBreaker)(nil).GetLevelLatch), arg0, arg1)
+}
+
+// GetState mocks base method.
+func (m *MockCircuitBreaker) GetState(arg0 context.Context) (circuitbreaker.State, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetState", arg0)
+	ret0, _ := ret[0].(circuitbreaker.State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetState indicates an expected call of GetState.
+func (mr *MockCircuitBreakerMockRecorder) GetState(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetState", reflect.TypeOf((*MockCircuitBreaker)(nil).GetState), arg0)
+}
+
 // GetTrip mocks base method.
 func (m *MockCircuitBreaker) GetTrip(arg0 context.Context) (bool, error) {
 	m.ctrl.T.Helper()
@@ -108,8 +227,37 @@ func (mr *MockCircuitBreakerMockRecorder) GetWindowDurationStr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWindowDurationStr", reflect.TypeOf((*MockCircuitBreaker)(nil).GetWindowDurationStr))
 }
 
+// Guard mocks base method.
+func (m *MockCircuitBreaker) Guard(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Guard", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Guard indicates an expected call of Guard.
+func (mr *MockCircuitBreakerMockRecorder) Guard(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Guard", reflect.TypeOf((*MockCircuitBreaker)(nil).Guard), arg0, arg1)
+}
+
+// IsExceedingLatency mocks base method.
+func (m *MockCircuitBreaker) IsExceedingLatency(arg0 context.Context, arg1 float64, arg2 time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsExceedingLatency", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsExceedingLatency indicates an expected call of IsExceedingLatency.
+func (mr *MockCircuitBreakerMockRecorder) IsExceedingLatency(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsExceedingLatency", reflect.TypeOf((*MockCircuitBreaker)(nil).IsExceedingLatency), arg0, arg1, arg2)
+}
+
 // IsExceedingThreshold mocks base method.
-func (m *MockCircuitBreaker) IsExceedingThreshold(arg0 context.Context, arg1 int) (bool, error) {
+func (m *MockCircuitBreaker) IsExceedingThreshold(arg0 context.Context, arg1 int64) (bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "IsExceedingThreshold", arg0, arg1)
 	ret0, _ := ret[0].(bool)
@@ -123,6 +271,150 @@ func (mr *MockCircuitBreakerMockRecorder) IsExceedingThreshold(arg0, arg1 interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsExceedingThreshold", reflect.TypeOf((*MockCircuitBreaker)(nil).IsExceedingThreshold), arg0, arg1)
 }
 
+// IsExceedingWarningThreshold mocks base method.
+func (m *MockCircuitBreaker) IsExceedingWarningThreshold(arg0 context.Context, arg1 int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsExceedingWarningThreshold", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsExceedingWarningThreshold indicates an expected call of IsExceedingWarningThreshold.
+func (mr *MockCircuitBreakerMockRecorder) IsExceedingWarningThreshold(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsExceedingWarningThreshold", reflect.TypeOf((*MockCircuitBreaker)(nil).IsExceedingWarningThreshold), arg0, arg1)
+}
+
+// Level mocks base method.
+func (m *MockCircuitBreaker) Level(arg0 context.Context, arg1 int64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Level", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Level indicates an expected call of Level.
+func (mr *MockCircuitBreakerMockRecorder) Level(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Level", reflect.TypeOf((*MockCircuitBreaker)(nil).Level), arg0, arg1)
+}
+
+// Record mocks base method.
+func (m *MockCircuitBreaker) Record(arg0 context.Context, arg1 int64) (circuitbreaker.Transitions, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Record", arg0, arg1)
+	ret0, _ := ret[0].(circuitbreaker.Transitions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockCircuitBreakerMockRecorder) Record(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockCircuitBreaker)(nil).Record), arg0, arg1)
+}
+
+// RecordCall mocks base method.
+func (m *MockCircuitBreaker) RecordCall(arg0 context.Context, arg1 bool, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordCall", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordCall indicates an expected call of RecordCall.
+func (mr *MockCircuitBreakerMockRecorder) RecordCall(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordCall", reflect.TypeOf((*MockCircuitBreaker)(nil).RecordCall), arg0, arg1, arg2)
+}
+
+// RecordFailure mocks base method.
+func (m *MockCircuitBreaker) RecordFailure(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordFailure", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordFailure indicates an expected call of RecordFailure.
+func (mr *MockCircuitBreakerMockRecorder) RecordFailure(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordFailure", reflect.TypeOf((*MockCircuitBreaker)(nil).RecordFailure), arg0)
+}
+
+// RecordLatency mocks base method.
+func (m *MockCircuitBreaker) RecordLatency(arg0 context.Context, arg1 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordLatency", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordLatency indicates an expected call of RecordLatency.
+func (mr *MockCircuitBreakerMockRecorder) RecordLatency(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordLatency", reflect.TypeOf((*MockCircuitBreaker)(nil).RecordLatency), arg0, arg1)
+}
+
+// RecordResult mocks base method.
+func (m *MockCircuitBreaker) RecordResult(arg0 context.Context, arg1 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordResult", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordResult indicates an expected call of RecordResult.
+func (mr *MockCircuitBreakerMockRecorder) RecordResult(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordResult", reflect.TypeOf((*MockCircuitBreaker)(nil).RecordResult), arg0, arg1)
+}
+
+// RecordSuccess mocks base method.
+func (m *MockCircuitBreaker) RecordSuccess(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordSuccess", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordSuccess indicates an expected call of RecordSuccess.
+func (mr *MockCircuitBreakerMockRecorder) RecordSuccess(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordSuccess", reflect.TypeOf((*MockCircuitBreaker)(nil).RecordSuccess), arg0)
+}
+
+// ReleaseProbe mocks base method.
+func (m *MockCircuitBreaker) ReleaseProbe(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseProbe", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseProbe indicates an expected call of ReleaseProbe.
+func (mr *MockCircuitBreakerMockRecorder) ReleaseProbe(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseProbe", reflect.TypeOf((*MockCircuitBreaker)(nil).ReleaseProbe), arg0)
+}
+
+// Reserve mocks base method.
+func (m *MockCircuitBreaker) Reserve(arg0 context.Context, arg1 int64) (circuitbreaker.Reservation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", arg0, arg1)
+	ret0, _ := ret[0].(circuitbreaker.Reservation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockCircuitBreakerMockRecorder) Reserve(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockCircuitBreaker)(nil).Reserve), arg0, arg1)
+}
+
 // SetActive mocks base method.
 func (m *MockCircuitBreaker) SetActive(arg0 bool) {
 	m.ctrl.T.Helper()
@@ -135,8 +427,104 @@ func (mr *MockCircuitBreakerMockRecorder) SetActive(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetActive", reflect.TypeOf((*MockCircuitBreaker)(nil).SetActive), arg0)
 }
 
+// SetAutoTrip mocks base method.
+func (m *MockCircuitBreaker) SetAutoTrip(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetAutoTrip", arg0)
+}
+
+// SetAutoTrip indicates an expected call of SetAutoTrip.
+func (mr *MockCircuitBreakerMockRecorder) SetAutoTrip(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAutoTrip", reflect.TypeOf((*MockCircuitBreaker)(nil).SetAutoTrip), arg0)
+}
+
+// SetLatencyConfig mocks base method.
+func (m *MockCircuitBreaker) SetLatencyConfig(arg0 circuitbreaker.LatencyConfig) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetLatencyConfig", arg0)
+}
+
+// SetLatencyConfig indicates an expected call of SetLatencyConfig.
+func (mr *MockCircuitBreakerMockRecorder) SetLatencyConfig(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLatencyConfig", reflect.TypeOf((*MockCircuitBreaker)(nil).SetLatencyConfig), arg0)
+}
+
+// SetLimitProvider mocks base method.
+func (m *MockCircuitBreaker) SetLimitProvider(arg0 circuitbreaker.LimitProvider) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetLimitProvider", arg0)
+}
+
+// SetLimitProvider indicates an expected call of SetLimitProvider.
+func (mr *MockCircuitBreakerMockRecorder) SetLimitProvider(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLimitProvider", reflect.TypeOf((*MockCircuitBreaker)(nil).SetLimitProvider), arg0)
+}
+
+// SetLookupStrategy mocks base method.
+func (m *MockCircuitBreaker) SetLookupStrategy(arg0 circuitbreaker.LookupStrategy) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetLookupStrategy", arg0)
+}
+
+// SetLookupStrategy indicates an expected call of SetLookupStrategy.
+func (mr *MockCircuitBreakerMockRecorder) SetLookupStrategy(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLookupStrategy", reflect.TypeOf((*MockCircuitBreaker)(nil).SetLookupStrategy), arg0)
+}
+
+// SetRatioConfig mocks base method.
+func (m *MockCircuitBreaker) SetRatioConfig(arg0 circuitbreaker.RatioConfig) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetRatioConfig", arg0)
+}
+
+// SetRatioConfig indicates an expected call of SetRatioConfig.
+func (mr *MockCircuitBreakerMockRecorder) SetRatioConfig(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRatioConfig", reflect.TypeOf((*MockCircuitBreaker)(nil).SetRatioConfig), arg0)
+}
+
+// SetRecordPolicy mocks base method.
+func (m *MockCircuitBreaker) SetRecordPolicy(arg0 circuitbreaker.RecordPolicy) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetRecordPolicy", arg0)
+}
+
+// SetRecordPolicy indicates an expected call of SetRecordPolicy.
+func (mr *MockCircuitBreakerMockRecorder) SetRecordPolicy(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRecordPolicy", reflect.TypeOf((*MockCircuitBreaker)(nil).SetRecordPolicy), arg0)
+}
+
+// SetReservationTimeout mocks base method.
+func (m *MockCircuitBreaker) SetReservationTimeout(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReservationTimeout", arg0)
+}
+
+// SetReservationTimeout indicates an expected call of SetReservationTimeout.
+func (mr *MockCircuitBreakerMockRecorder) SetReservationTimeout(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReservationTimeout", reflect.TypeOf((*MockCircuitBreaker)(nil).SetReservationTimeout), arg0)
+}
+
+// SetStateMachineConfig mocks base method.
+func (m *MockCircuitBreaker) SetStateMachineConfig(arg0 circuitbreaker.StateMachineConfig) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetStateMachineConfig", arg0)
+}
+
+// SetStateMachineConfig indicates an expected call of SetStateMachineConfig.
+func (mr *MockCircuitBreakerMockRecorder) SetStateMachineConfig(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStateMachineConfig", reflect.TypeOf((*MockCircuitBreaker)(nil).SetStateMachineConfig), arg0)
+}
+
 // SetThreshold mocks base method.
-func (m *MockCircuitBreaker) SetThreshold(arg0 int) {
+func (m *MockCircuitBreaker) SetThreshold(arg0 int64) {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "SetThreshold", arg0)
 }
@@ -147,10 +535,66 @@ func (mr *MockCircuitBreakerMockRecorder) SetThreshold(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetThreshold", reflect.TypeOf((*MockCircuitBreaker)(nil).SetThreshold), arg0)
 }
 
+// SetThresholdLevels mocks base method.
+func (m *MockCircuitBreaker) SetThresholdLevels(arg0 []circuitbreaker.ThresholdLevel) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetThresholdLevels", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetThresholdLevels indicates an expected call of SetThresholdLevels.
+func (mr *MockCircuitBreakerMockRecorder) SetThresholdLevels(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetThresholdLevels", reflect.TypeOf((*MockCircuitBreaker)(nil).SetThresholdLevels), arg0)
+}
+
+// SetWarningPercentage mocks base method.
+func (m *MockCircuitBreaker) SetWarningPercentage(arg0 float64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetWarningPercentage", arg0)
+}
+
+// SetWarningPercentage indicates an expected call of SetWarningPercentage.
+func (mr *MockCircuitBreakerMockRecorder) SetWarningPercentage(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWarningPercentage", reflect.TypeOf((*MockCircuitBreaker)(nil).SetWarningPercentage), arg0)
+}
+
+// SetWarningThreshold mocks base method.
+func (m *MockCircuitBreaker) SetWarningThreshold(arg0 int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetWarningThreshold", arg0)
+}
+
+// SetWarningThreshold indicates an expected call of SetWarningThreshold.
+func (mr *MockCircuitBreakerMockRecorder) SetWarningThreshold(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWarningThreshold", reflect.TypeOf((*MockCircuitBreaker)(nil).SetWarningThreshold), arg0)
+}
+
+// TryConsume mocks base method.
+func (m *MockCircuitBreaker) TryConsume(arg0 context.Context, arg1 int64) (bool, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryConsume", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// TryConsume indicates an expected call of TryConsume.
+func (mr *MockCircuitBreakerMockRecorder) TryConsume(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryConsume", reflect.TypeOf((*MockCircuitBreaker)(nil).TryConsume), arg0, arg1)
+}
+
 // UpdateLatestBucketsValue mocks base method.
-func (m *MockCircuitBreaker) UpdateLatestBucketsValue(arg0 context.Context, arg1 int) {
+func (m *MockCircuitBreaker) UpdateLatestBucketsValue(arg0 context.Context, arg1 int64) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "UpdateLatestBucketsValue", arg0, arg1)
+	ret := m.ctrl.Call(m, "UpdateLatestBucketsValue", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // UpdateLatestBucketsValue indicates an expected call of UpdateLatestBucketsValue.
@@ -160,9 +604,11 @@ func (mr *MockCircuitBreakerMockRecorder) UpdateLatestBucketsValue(arg0, arg1 in
 }
 
 // UpdateTrip mocks base method.
-func (m *MockCircuitBreaker) UpdateTrip(arg0 context.Context, arg1 bool) {
+func (m *MockCircuitBreaker) UpdateTrip(arg0 context.Context, arg1 bool) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "UpdateTrip", arg0, arg1)
+	ret := m.ctrl.Call(m, "UpdateTrip", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // UpdateTrip indicates an expected call of UpdateTrip.
@@ -172,9 +618,11 @@ func (mr *MockCircuitBreakerMockRecorder) UpdateTrip(arg0, arg1 interface{}) *go
 }
 
 // UpdateTripWarning mocks base method.
-func (m *MockCircuitBreaker) UpdateTripWarning(arg0 context.Context, arg1 bool) {
+func (m *MockCircuitBreaker) UpdateTripWarning(arg0 context.Context, arg1 bool) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "UpdateTripWarning", arg0, arg1)
+	ret := m.ctrl.Call(m, "UpdateTripWarning", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // UpdateTripWarning indicates an expected call of UpdateTripWarning.
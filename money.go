@@ -0,0 +1,121 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidMoney     = errors.New("invalid money")
+)
+
+// maxCurrencyScale keeps 10^scale within int64
+const maxCurrencyScale = 18
+
+// Currency is an ISO 4217 code and how many decimals its minor unit has
+// example: Currency{Code: "USD", Scale: 2}, Currency{Code: "IDR", Scale: 0}
+type Currency struct {
+	Code  string
+	Scale int
+}
+
+// Money is an amount in minor units of Currency, so it is exact
+// example: Money{Minor: 1050, Currency: Currency{Code: "USD", Scale: 2}} is 10.50 USD
+type Money struct {
+	Minor    int64
+	Currency Currency
+}
+
+// ParseMoney parses a decimal string such as "10.50" into minor units of currency
+// no digits or more decimals than the currency scale is ErrInvalidMoney, a value not fitting int64 is ErrAmountOverflow
+func ParseMoney(value string, currency Currency) (Money, error) {
+	if currency.Scale < 0 || currency.Scale > maxCurrencyScale {
+		return Money{}, fmt.Errorf("%w: scale %d of %s", ErrInvalidMoney, currency.Scale, currency.Code)
+	}
+
+	whole, fraction, _ := strings.Cut(value, ".")
+	// strconv.ParseInt would read "", "-" or "." padded to the scale as zero
+	if strings.TrimLeft(whole, "+-")+fraction == "" {
+		return Money{}, fmt.Errorf("%w: %q has no digits", ErrInvalidMoney, value)
+	}
+	if len(fraction) > currency.Scale {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals", ErrInvalidMoney, value, currency.Scale)
+	}
+	if strings.ContainsAny(fraction, "+-") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, value)
+	}
+
+	// the fraction is padded to the scale, so the digits read as minor units
+	minor, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", currency.Scale-len(fraction)), 10, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return Money{}, fmt.Errorf("%w: %q", ErrAmountOverflow, value)
+	}
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, value)
+	}
+
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// String formats money with its currency scale
+// example: 10.50 USD
+func (m Money) String() string {
+	if m.Currency.Scale <= 0 {
+		return fmt.Sprintf("%d %s", m.Minor, m.Currency.Code)
+	}
+
+	digits := strconv.FormatUint(absInt64(m.Minor), 10)
+	if len(digits) <= m.Currency.Scale {
+		digits = strings.Repeat("0", m.Currency.Scale-len(digits)+1) + digits
+	}
+	sign := ""
+	if m.Minor < 0 {
+		sign = "-"
+	}
+	point := len(digits) - m.Currency.Scale
+
+	return fmt.Sprintf("%s%s.%s %s", sign, digits[:point], digits[point:], m.Currency.Code)
+}
+
+// rescale converts minor units of scale from into minor units of scale to
+// scaling down is only allowed when nothing is lost
+func rescale(minor int64, from int, to int) (int64, error) {
+	if from < 0 || from > maxCurrencyScale || to < 0 || to > maxCurrencyScale {
+		return 0, fmt.Errorf("%w: scale %d into %d", ErrInvalidMoney, from, to)
+	}
+
+	factor := int64(math.Pow10(absInt(to - from)))
+	if to < from {
+		if minor%factor != 0 {
+			return 0, fmt.Errorf("%w: %d has more than %d decimals", ErrInvalidMoney, minor, to)
+		}
+		return minor / factor, nil
+	}
+
+	if minor > math.MaxInt64/factor || minor < math.MinInt64/factor {
+		return 0, ErrAmountOverflow
+	}
+
+	return minor * factor, nil
+}
+
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
+
+// absInt64 returns the magnitude as uint64, so math.MinInt64 doesn't overflow
+func absInt64(value int64) uint64 {
+	if value < 0 {
+		return uint64(-(value + 1)) + 1
+	}
+
+	return uint64(value)
+}
@@ -0,0 +1,176 @@
+package circuitbreaker
+
+import (
+	"context"
+	"fmt"
+)
+
+// MoneyBreaker is a circuit breaker counting money of one currency, every amount is Money and is checked against it
+// thresholds and buckets are in minor units of the currency
+type MoneyBreaker interface {
+	Allow(ctx context.Context) (bool, error)
+	CalculateWindowValue(ctx context.Context) (Money, error)
+	Execute(ctx context.Context, amount Money, fn func(ctx context.Context) error) error
+	GetCurrency() Currency
+	GetState(ctx context.Context) (State, error)
+	IsExceedingThreshold(ctx context.Context, amount Money) (bool, error)
+	IsExceedingWarningThreshold(ctx context.Context, amount Money) (bool, error)
+	Record(ctx context.Context, amount Money) (Transitions, error)
+	RecordResult(ctx context.Context, success bool) error
+	Reserve(ctx context.Context, amount Money) (Reservation, error)
+	SetThreshold(threshold Money) error
+	SetWarningThreshold(threshold Money) error
+	TryConsume(ctx context.Context, amount Money) (bool, Money, error)
+	UpdateLatestBucketsValue(ctx context.Context, amount Money) error
+	UpdateTrip(ctx context.Context, isTripped bool) error
+}
+
+type moneyBreaker struct {
+	CircuitBreaker *circuitBreaker
+}
+
+// NewMoneyBreaker creates MoneyBreaker from the same options as New, WithCurrency is required
+// the circuit breaker is only reachable through Money, so money in another currency can never be counted
+func NewMoneyBreaker(featureName string, opts ...Option) (MoneyBreaker, error) {
+	circuitBreaker := newCircuitBreakerFromOptions(featureName, opts...)
+	if circuitBreaker.Currency.Code == "" {
+		return nil, &ConfigError{Field: "currency", Message: "must be set"}
+	}
+	if err := circuitBreaker.validate(); err != nil {
+		return nil, err
+	}
+	circuitBreaker.init()
+
+	return &moneyBreaker{
+		CircuitBreaker: circuitBreaker,
+	}, nil
+}
+
+func (m *moneyBreaker) Allow(ctx context.Context) (bool, error) {
+	return m.CircuitBreaker.Allow(ctx)
+}
+
+func (m *moneyBreaker) CalculateWindowValue(ctx context.Context) (Money, error) {
+	windowValue, err := m.CircuitBreaker.CalculateWindowValue(ctx)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return m.money(windowValue), nil
+}
+
+func (m *moneyBreaker) Execute(ctx context.Context, amount Money, fn func(ctx context.Context) error) error {
+	minor, err := m.minorUnits(amount)
+	if err != nil {
+		return err
+	}
+
+	return m.CircuitBreaker.Execute(ctx, minor, fn)
+}
+
+// GetCurrency returns the currency every amount must be in
+func (m *moneyBreaker) GetCurrency() Currency {
+	return m.CircuitBreaker.Currency
+}
+
+func (m *moneyBreaker) GetState(ctx context.Context) (State, error) {
+	return m.CircuitBreaker.GetState(ctx)
+}
+
+func (m *moneyBreaker) IsExceedingThreshold(ctx context.Context, amount Money) (bool, error) {
+	minor, err := m.minorUnits(amount)
+	if err != nil {
+		return false, err
+	}
+
+	return m.CircuitBreaker.IsExceedingThreshold(ctx, minor)
+}
+
+func (m *moneyBreaker) IsExceedingWarningThreshold(ctx context.Context, amount Money) (bool, error) {
+	minor, err := m.minorUnits(amount)
+	if err != nil {
+		return false, err
+	}
+
+	return m.CircuitBreaker.IsExceedingWarningThreshold(ctx, minor)
+}
+
+func (m *moneyBreaker) Record(ctx context.Context, amount Money) (Transitions, error) {
+	minor, err := m.minorUnits(amount)
+	if err != nil {
+		return Transitions{}, err
+	}
+
+	return m.CircuitBreaker.Record(ctx, minor)
+}
+
+func (m *moneyBreaker) RecordResult(ctx context.Context, success bool) error {
+	return m.CircuitBreaker.RecordResult(ctx, success)
+}
+
+func (m *moneyBreaker) Reserve(ctx context.Context, amount Money) (Reservation, error) {
+	minor, err := m.minorUnits(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.CircuitBreaker.Reserve(ctx, minor)
+}
+
+func (m *moneyBreaker) SetThreshold(threshold Money) error {
+	minor, err := m.minorUnits(threshold)
+	if err != nil {
+		return err
+	}
+	m.CircuitBreaker.SetThreshold(minor)
+
+	return nil
+}
+
+func (m *moneyBreaker) SetWarningThreshold(threshold Money) error {
+	minor, err := m.minorUnits(threshold)
+	if err != nil {
+		return err
+	}
+	m.CircuitBreaker.SetWarningThreshold(minor)
+
+	return nil
+}
+
+func (m *moneyBreaker) TryConsume(ctx context.Context, amount Money) (bool, Money, error) {
+	minor, err := m.minorUnits(amount)
+	if err != nil {
+		return false, Money{}, err
+	}
+
+	allowed, windowValue, err := m.CircuitBreaker.TryConsume(ctx, minor)
+	return allowed, m.money(windowValue), err
+}
+
+func (m *moneyBreaker) UpdateLatestBucketsValue(ctx context.Context, amount Money) error {
+	minor, err := m.minorUnits(amount)
+	if err != nil {
+		return err
+	}
+
+	return m.CircuitBreaker.UpdateLatestBucketsValue(ctx, minor)
+}
+
+func (m *moneyBreaker) UpdateTrip(ctx context.Context, isTripped bool) error {
+	return m.CircuitBreaker.UpdateTrip(ctx, isTripped)
+}
+
+// minorUnits converts money into the minor units the circuit breaker counts
+// money in another currency is ErrCurrencyMismatch, money with a different scale of the same currency is rescaled
+func (m *moneyBreaker) minorUnits(money Money) (int64, error) {
+	currency := m.CircuitBreaker.Currency
+	if money.Currency.Code != currency.Code {
+		return 0, fmt.Errorf("%w: %s, circuit breaker counts %s", ErrCurrencyMismatch, money, currency.Code)
+	}
+
+	return rescale(money.Minor, money.Currency.Scale, currency.Scale)
+}
+
+func (m *moneyBreaker) money(minor int64) Money {
+	return Money{Minor: minor, Currency: m.CircuitBreaker.Currency}
+}
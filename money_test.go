@@ -0,0 +1,303 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	goCache "github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+)
+
+var (
+	USD = circuitbreaker.Currency{Code: "USD", Scale: 2}
+	IDR = circuitbreaker.Currency{Code: "IDR", Scale: 0}
+)
+
+func TestParseMoney(t *testing.T) {
+	type Request struct {
+		value    string
+		currency circuitbreaker.Currency
+	}
+
+	type Response struct {
+		money circuitbreaker.Money
+		str   string
+		err   error
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Decimal amount": {
+			request: Request{
+				value:    "10.50",
+				currency: USD,
+			},
+			response: Response{
+				money: circuitbreaker.Money{Minor: 1050, Currency: USD},
+				str:   "10.50 USD",
+			},
+		},
+		"Fraction shorter than scale": {
+			request: Request{
+				value:    "10.5",
+				currency: USD,
+			},
+			response: Response{
+				money: circuitbreaker.Money{Minor: 1050, Currency: USD},
+				str:   "10.50 USD",
+			},
+		},
+		"Whole amount": {
+			request: Request{
+				value:    "250000",
+				currency: IDR,
+			},
+			response: Response{
+				money: circuitbreaker.Money{Minor: 250000, Currency: IDR},
+				str:   "250000 IDR",
+			},
+		},
+		"Negative amount below one": {
+			request: Request{
+				value:    "-0.05",
+				currency: USD,
+			},
+			response: Response{
+				money: circuitbreaker.Money{Minor: -5, Currency: USD},
+				str:   "-0.05 USD",
+			},
+		},
+		"More decimals than scale": {
+			request: Request{
+				value:    "10.505",
+				currency: USD,
+			},
+			response: Response{
+				err: circuitbreaker.ErrInvalidMoney,
+			},
+		},
+		"Not a number": {
+			request: Request{
+				value:    "ten",
+				currency: USD,
+			},
+			response: Response{
+				err: circuitbreaker.ErrInvalidMoney,
+			},
+		},
+		"Sign inside the fraction": {
+			request: Request{
+				value:    "10.-5",
+				currency: USD,
+			},
+			response: Response{
+				err: circuitbreaker.ErrInvalidMoney,
+			},
+		},
+		"Empty": {
+			request: Request{
+				value:    "",
+				currency: USD,
+			},
+			response: Response{
+				err: circuitbreaker.ErrInvalidMoney,
+			},
+		},
+		"Only a point": {
+			request: Request{
+				value:    ".",
+				currency: USD,
+			},
+			response: Response{
+				err: circuitbreaker.ErrInvalidMoney,
+			},
+		},
+		"Only a sign": {
+			request: Request{
+				value:    "-",
+				currency: USD,
+			},
+			response: Response{
+				err: circuitbreaker.ErrInvalidMoney,
+			},
+		},
+		"Sign and point": {
+			request: Request{
+				value:    "-.",
+				currency: USD,
+			},
+			response: Response{
+				err: circuitbreaker.ErrInvalidMoney,
+			},
+		},
+		"Overflow": {
+			request: Request{
+				value:    "92233720368547758.08",
+				currency: USD,
+			},
+			response: Response{
+				err: circuitbreaker.ErrAmountOverflow,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			money, err := circuitbreaker.ParseMoney(tc.request.value, tc.request.currency)
+			assert.ErrorIs(t, err, tc.response.err)
+			assert.Equal(t, tc.response.money, money)
+			if tc.response.err == nil {
+				assert.Equal(t, tc.response.str, money.String())
+			}
+		})
+	}
+}
+
+func TestMoney_StringMinInt64(t *testing.T) {
+	money := circuitbreaker.Money{Minor: math.MinInt64, Currency: USD}
+	assert.Equal(t, "-92233720368547758.08 USD", money.String())
+}
+
+func TestMoneyBreaker_UpdateLatestBucketsValue(t *testing.T) {
+	type Request struct {
+		money circuitbreaker.Money
+	}
+
+	type Response struct {
+		windowValue circuitbreaker.Money
+		err         error
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Same currency and scale": {
+			request: Request{
+				money: circuitbreaker.Money{Minor: 1050, Currency: USD},
+			},
+			response: Response{
+				windowValue: circuitbreaker.Money{Minor: 1050, Currency: USD},
+			},
+		},
+		"Smaller scale is scaled up": {
+			request: Request{
+				money: circuitbreaker.Money{Minor: 10, Currency: circuitbreaker.Currency{Code: "USD"}},
+			},
+			response: Response{
+				windowValue: circuitbreaker.Money{Minor: 1000, Currency: USD},
+			},
+		},
+		"Larger scale without remainder is scaled down": {
+			request: Request{
+				money: circuitbreaker.Money{Minor: 105000, Currency: circuitbreaker.Currency{Code: "USD", Scale: 4}},
+			},
+			response: Response{
+				windowValue: circuitbreaker.Money{Minor: 1050, Currency: USD},
+			},
+		},
+		"Larger scale with remainder": {
+			request: Request{
+				money: circuitbreaker.Money{Minor: 105001, Currency: circuitbreaker.Currency{Code: "USD", Scale: 4}},
+			},
+			response: Response{
+				windowValue: circuitbreaker.Money{Currency: USD},
+				err:         circuitbreaker.ErrInvalidMoney,
+			},
+		},
+		"Scaling up overflows": {
+			request: Request{
+				money: circuitbreaker.Money{Minor: math.MaxInt64 / 10, Currency: circuitbreaker.Currency{Code: "USD"}},
+			},
+			response: Response{
+				windowValue: circuitbreaker.Money{Currency: USD},
+				err:         circuitbreaker.ErrAmountOverflow,
+			},
+		},
+		"Other currency": {
+			request: Request{
+				money: circuitbreaker.Money{Minor: 1050, Currency: IDR},
+			},
+			response: Response{
+				windowValue: circuitbreaker.Money{Currency: USD},
+				err:         circuitbreaker.ErrCurrencyMismatch,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cb := newMoneyBreaker(t)
+
+			err := cb.UpdateLatestBucketsValue(ctx, tc.request.money)
+			assert.ErrorIs(t, err, tc.response.err)
+
+			windowValue, err := cb.CalculateWindowValue(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.windowValue, windowValue)
+		})
+	}
+}
+
+func TestMoneyBreaker_Threshold(t *testing.T) {
+	ctx := context.Background()
+	cb := newMoneyBreaker(t)
+
+	threshold, err := circuitbreaker.ParseMoney("100.00", USD)
+	assert.Nil(t, err)
+	assert.Nil(t, cb.SetThreshold(threshold))
+	assert.ErrorIs(t, cb.SetThreshold(circuitbreaker.Money{Minor: 100, Currency: IDR}), circuitbreaker.ErrCurrencyMismatch)
+
+	payment, err := circuitbreaker.ParseMoney("60.25", USD)
+	assert.Nil(t, err)
+	allowed, windowValue, err := cb.TryConsume(ctx, payment)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, "60.25 USD", windowValue.String())
+
+	isExceeding, err := cb.IsExceedingThreshold(ctx, payment)
+	assert.Nil(t, err)
+	assert.True(t, isExceeding)
+
+	// money in another currency is never counted, whatever the breaker is asked to do
+	_, err = cb.Reserve(ctx, circuitbreaker.Money{Minor: 1, Currency: IDR})
+	assert.ErrorIs(t, err, circuitbreaker.ErrCurrencyMismatch)
+	err = cb.Execute(ctx, circuitbreaker.Money{Minor: 1, Currency: IDR}, func(ctx context.Context) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, circuitbreaker.ErrCurrencyMismatch)
+}
+
+func TestMoneyBreaker_New(t *testing.T) {
+	cache := circuitbreaker.NewCache(circuitbreaker.NewGoCacheAdapter(goCache.New(time.Hour, time.Hour)), time.Hour)
+
+	_, err := circuitbreaker.NewMoneyBreaker("test", circuitbreaker.WithCache(cache))
+	assert.ErrorIs(t, err, circuitbreaker.ErrInvalidConfig)
+
+	_, err = circuitbreaker.NewMoneyBreaker("test", circuitbreaker.WithCache(cache), circuitbreaker.WithCurrency(circuitbreaker.Currency{Code: "USD", Scale: 19}))
+	assert.ErrorIs(t, err, circuitbreaker.ErrInvalidConfig)
+
+	// only NewMoneyBreaker counts money
+	_, err = circuitbreaker.NewKeyedBreaker("test", 10, circuitbreaker.WithCache(cache), circuitbreaker.WithCurrency(USD))
+	assert.ErrorIs(t, err, circuitbreaker.ErrInvalidConfig)
+}
+
+func newMoneyBreaker(t *testing.T) circuitbreaker.MoneyBreaker {
+	t.Helper()
+
+	cb, err := circuitbreaker.NewMoneyBreaker(
+		"test",
+		circuitbreaker.WithCache(circuitbreaker.NewCache(circuitbreaker.NewGoCacheAdapter(goCache.New(time.Hour, time.Hour)), time.Hour)),
+		circuitbreaker.WithCurrency(USD),
+	)
+	assert.Nil(t, err)
+
+	return cb
+}
@@ -0,0 +1,111 @@
+package circuitbreaker
+
+import "time"
+
+type Option func(c *circuitBreaker)
+
+// WithBuckets sets buckets used to store and look up the window, the slice is copied
+func WithBuckets(buckets ...*Bucket) Option {
+	return func(c *circuitBreaker) {
+		c.Buckets = append([]*Bucket{}, buckets...)
+	}
+}
+
+// WithCache sets where buckets, trip and warning alert are stored
+func WithCache(cache Cache) Option {
+	return func(c *circuitBreaker) {
+		c.Cache = cache
+	}
+}
+
+// WithCacheTTL sets how long every time point key lives
+func WithCacheTTL(cacheTTL time.Duration) Option {
+	return func(c *circuitBreaker) {
+		c.CacheTTL = cacheTTL
+	}
+}
+
+// WithClock replaces the system clock, e.g. to replay historical traffic or test bucket rollover
+func WithClock(clock Clock) Option {
+	return func(c *circuitBreaker) {
+		c.Clock = clock
+	}
+}
+
+// WithCurrency sets the currency of NewMoneyBreaker, thresholds are in its minor units
+// New and NewKeyedBreaker reject it, their amounts are not money
+func WithCurrency(currency Currency) Option {
+	return func(c *circuitBreaker) {
+		c.Currency = currency
+	}
+}
+
+// WithKeyPrefix replaces DefaultKeyPrefix of every key
+func WithKeyPrefix(keyPrefix string) Option {
+	return func(c *circuitBreaker) {
+		c.KeyPrefix = keyPrefix
+	}
+}
+
+// WithLatency sets latency histogram bounds and enables latency tripping
+func WithLatency(config LatencyConfig) Option {
+	return func(c *circuitBreaker) {
+		c.LatencyConfig = config
+	}
+}
+
+// WithLimitProvider makes the threshold follow provider, wrap it with NewCachedLimitProvider to avoid a call per check
+func WithLimitProvider(provider LimitProvider) Option {
+	return func(c *circuitBreaker) {
+		c.LimitProvider = provider
+	}
+}
+
+// WithLookupStrategy sets how the window keys are generated
+func WithLookupStrategy(strategy LookupStrategy) Option {
+	return func(c *circuitBreaker) {
+		c.LookupStrategy = strategy
+	}
+}
+
+// WithRatio enables ratio mode, the circuit trips on failure rate
+func WithRatio(config RatioConfig) Option {
+	return func(c *circuitBreaker) {
+		c.RatioConfig = config
+	}
+}
+
+// WithThreshold sets threshold of the window value
+func WithThreshold(threshold int64) Option {
+	return func(c *circuitBreaker) {
+		c.Threshold = threshold
+	}
+}
+
+// WithThresholdLevels replaces the default warning and trip levels, levels are sorted by threshold
+func WithThresholdLevels(levels ...ThresholdLevel) Option {
+	return func(c *circuitBreaker) {
+		c.setThresholdLevels(levels)
+	}
+}
+
+// WithWarningPercentage sets warning threshold as a percentage of the threshold
+func WithWarningPercentage(percentage float64) Option {
+	return func(c *circuitBreaker) {
+		c.WarningPercentage = percentage
+	}
+}
+
+// WithWarningThreshold sets warning threshold of the window value
+func WithWarningThreshold(threshold int64) Option {
+	return func(c *circuitBreaker) {
+		c.WarningThreshold = threshold
+	}
+}
+
+// WithWindow sets window duration
+func WithWindow(windowDuration time.Duration) Option {
+	return func(c *circuitBreaker) {
+		c.WindowDuration = windowDuration
+	}
+}
@@ -0,0 +1,47 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	ErrAmountOverflow = errors.New("amount overflows int64")
+)
+
+// addInt64 returns a + b, or ErrAmountOverflow when the sum doesn't fit int64
+func addInt64(a int64, b int64) (int64, error) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, ErrAmountOverflow
+	}
+
+	return sum, nil
+}
+
+// saturatingAdd returns a + b clamped to math.MinInt64 and math.MaxInt64
+// a saturated window value is still compared correctly against any threshold
+func saturatingAdd(a int64, b int64) int64 {
+	sum, err := addInt64(a, b)
+	if err == nil {
+		return sum
+	}
+	if b > 0 {
+		return math.MaxInt64
+	}
+
+	return math.MinInt64
+}
+
+// saturatingFloat converts value into int64 clamped to math.MinInt64 and math.MaxInt64
+// float64(math.MaxInt64) rounds up to 2^63, so it has to be compared before converting
+func saturatingFloat(value float64) int64 {
+	if value >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	if value <= math.MinInt64 {
+		return math.MinInt64
+	}
+
+	return int64(value)
+}
@@ -0,0 +1,70 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	goCache "github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/cbtest"
+)
+
+func TestCircuitBreaker_AmountOverflow(t *testing.T) {
+	ctx := context.Background()
+	clock := cbtest.NewClock(time.Date(2023, time.May, 9, 10, 42, 0, 0, time.UTC))
+	cb, err := circuitbreaker.New(
+		"test",
+		circuitbreaker.WithCache(circuitbreaker.NewCache(circuitbreaker.NewGoCacheAdapter(goCache.New(time.Hour, time.Hour)), 28*time.Hour)),
+		circuitbreaker.WithCacheTTL(28*time.Hour),
+		circuitbreaker.WithClock(clock),
+	)
+	assert.Nil(t, err)
+
+	assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, math.MaxInt64-10))
+	assert.ErrorIs(t, cb.UpdateLatestBucketsValue(ctx, 100), circuitbreaker.ErrAmountOverflow)
+
+	// the rejected amount is not written to any bucket
+	windowValue, err := cb.CalculateWindowValue(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(math.MaxInt64-10), windowValue)
+
+	// window value + amount saturates instead of wrapping around to a negative value
+	isExceeding, err := cb.IsExceedingThreshold(ctx, 100)
+	assert.Nil(t, err)
+	assert.True(t, isExceeding)
+
+	allowed, _, err := cb.TryConsume(ctx, 100)
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+}
+
+func TestCircuitBreaker_SaturatedWindowValue(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	clock := cbtest.NewClock(time.Date(2023, time.May, 9, 10, 42, 0, 0, time.UTC))
+	cb, err := circuitbreaker.New(
+		"test",
+		circuitbreaker.WithCache(circuitbreaker.NewRedisCache(client, 28*time.Hour)),
+		circuitbreaker.WithCacheTTL(28*time.Hour),
+		circuitbreaker.WithClock(clock),
+		circuitbreaker.WithThreshold(math.MaxInt64),
+	)
+	assert.Nil(t, err)
+
+	// every bucket fits int64 on its own, their sum doesn't
+	assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, math.MaxInt64/2+1))
+	clock.Advance(5 * time.Hour)
+	assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, math.MaxInt64/2+1))
+
+	windowValue, err := cb.CalculateWindowValue(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(math.MaxInt64), windowValue)
+
+	isExceeding, err := cb.IsExceedingThreshold(ctx, 1)
+	assert.Nil(t, err)
+	assert.True(t, isExceeding)
+}
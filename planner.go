@@ -0,0 +1,151 @@
+package circuitbreaker
+
+import (
+	"sort"
+	"time"
+)
+
+// Amplification is the cost of a bucket set per operation
+// Write is the keys incremented per UpdateLatestBucketsValue, Read is the worst case keys emitted by GenerateKeys
+type Amplification struct {
+	Write int
+	Read  int
+}
+
+// PlanBuckets picks the bucket hierarchy with the fewest buckets whose worst case lookup fits in maxKeysPerLookup
+// ties are broken by the fewest lookup keys, when nothing fits the hierarchy with the fewest lookup keys is returned
+// minGranularity is the smallest bucket and defaults to one minute, a window that is not a multiple of it returns nil
+// example: PlanBuckets(24*time.Hour, 25, time.Minute)
+func PlanBuckets(window time.Duration, maxKeysPerLookup int, minGranularity time.Duration) []*Bucket {
+	if minGranularity <= 0 {
+		minGranularity = time.Minute
+	}
+	if window < minGranularity || window%minGranularity != 0 {
+		return nil
+	}
+
+	units := int64(window / minGranularity)
+	divisors := getDivisors(units)
+
+	// cost[i] is the minimum sum of (ratio - 1) for a chain from 1 to divisors[i] with the current number of steps
+	// parents[step][i] is the previous divisor index in that chain
+	cost := make([]int64, len(divisors))
+	for i := range cost {
+		cost[i] = -1
+	}
+	cost[0] = 0
+	parents := [][]int{}
+
+	bestSteps, bestTop := -1, -1
+	var bestRead int64
+	fitted := false
+
+	for steps := 0; ; steps++ {
+		if steps > 0 {
+			next := make([]int64, len(divisors))
+			parent := make([]int, len(divisors))
+			for i := range next {
+				next[i] = -1
+				for j := 0; j < i; j++ {
+					if cost[j] < 0 || divisors[i]%divisors[j] != 0 {
+						continue
+					}
+					candidate := cost[j] + divisors[i]/divisors[j] - 1
+					if next[i] < 0 || candidate < next[i] {
+						next[i] = candidate
+						parent[i] = j
+					}
+				}
+			}
+			cost = next
+			parents = append(parents, parent)
+		}
+
+		reachable := false
+		for i, top := range divisors {
+			if cost[i] < 0 {
+				continue
+			}
+			reachable = true
+
+			read := 1 + units/top
+			if cost[i] > 0 {
+				// the largest bucket loop stops one bucket early when the smaller buckets are full
+				read = units/top + cost[i]
+			}
+			if bestTop < 0 || read < bestRead {
+				bestSteps, bestTop, bestRead = steps, i, read
+			}
+		}
+
+		if maxKeysPerLookup > 0 && bestRead <= int64(maxKeysPerLookup) {
+			fitted = true
+		}
+		if fitted || !reachable {
+			break
+		}
+	}
+
+	buckets := []*Bucket{}
+	for steps, i := bestSteps, bestTop; ; steps-- {
+		buckets = append(buckets, NewBucket(time.Duration(divisors[i])*minGranularity))
+		if steps == 0 {
+			break
+		}
+		i = parents[steps-1][i]
+	}
+
+	return buckets
+}
+
+// EstimateAmplification returns the write and worst case read amplification of buckets for window
+func EstimateAmplification(window time.Duration, buckets []*Bucket) Amplification {
+	if len(buckets) == 0 {
+		return Amplification{}
+	}
+
+	durations := make([]time.Duration, 0, len(buckets))
+	for _, bucket := range buckets {
+		durations = append(durations, bucket.Duration)
+	}
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] > durations[j]
+	})
+
+	// one head key plus full largest buckets, or one largest bucket less and at most ceil(parent / child) - 1 keys per smaller bucket
+	read := 1 + int(window/durations[0])
+	if len(durations) > 1 {
+		smallest := durations[len(durations)-1]
+		partial := 1 + int((window-smallest)/durations[0])
+		for i := 1; i < len(durations); i++ {
+			partial += int((durations[i-1]+durations[i]-1)/durations[i]) - 1
+		}
+		if partial > read {
+			read = partial
+		}
+	}
+
+	return Amplification{
+		Write: len(durations),
+		Read:  read,
+	}
+}
+
+// getDivisors returns divisors of n in ascending order
+func getDivisors(n int64) []int64 {
+	small, large := []int64{}, []int64{}
+	for i := int64(1); i*i <= n; i++ {
+		if n%i != 0 {
+			continue
+		}
+		small = append(small, i)
+		if i != n/i {
+			large = append(large, n/i)
+		}
+	}
+	for i := len(large) - 1; i >= 0; i-- {
+		small = append(small, large[i])
+	}
+
+	return small
+}
@@ -0,0 +1,168 @@
+package circuitbreaker_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/fixture"
+)
+
+func TestPlanner_PlanBuckets(t *testing.T) {
+	type Request struct {
+		window           time.Duration
+		maxKeysPerLookup int
+		minGranularity   time.Duration
+	}
+
+	type Response struct {
+		names         []string
+		amplification circuitbreaker.Amplification
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"PlanBuckets one day window success": {
+			request: Request{
+				window:           24 * time.Hour,
+				maxKeysPerLookup: 25,
+				minGranularity:   time.Minute,
+			},
+			response: Response{
+				names:         []string{"3h", "30m", "5m", "1m"},
+				amplification: circuitbreaker.Amplification{Write: 4, Read: 22},
+			},
+		},
+		"PlanBuckets generous budget uses fewer buckets": {
+			request: Request{
+				window:           24 * time.Hour,
+				maxKeysPerLookup: 60,
+				minGranularity:   time.Minute,
+			},
+			response: Response{
+				names:         []string{"2h", "10m", "1m"},
+				amplification: circuitbreaker.Amplification{Write: 3, Read: 32},
+			},
+		},
+		"PlanBuckets budget too small returns fewest keys": {
+			request: Request{
+				window:           24 * time.Hour,
+				maxKeysPerLookup: 10,
+				minGranularity:   time.Minute,
+			},
+			response: Response{
+				names:         []string{"4h48m", "1h36m", "32m", "16m", "8m", "4m", "2m", "1m"},
+				amplification: circuitbreaker.Amplification{Write: 8, Read: 14},
+			},
+		},
+		"PlanBuckets second granularity success": {
+			request: Request{
+				window:           time.Hour,
+				maxKeysPerLookup: 30,
+				minGranularity:   time.Second,
+			},
+			response: Response{
+				names:         []string{"6m", "36s", "6s", "1s"},
+				amplification: circuitbreaker.Amplification{Write: 4, Read: 29},
+			},
+		},
+		"PlanBuckets default granularity": {
+			request: Request{
+				window:           time.Hour,
+				maxKeysPerLookup: 2,
+			},
+			response: Response{
+				names:         []string{"12m", "4m", "2m", "1m"},
+				amplification: circuitbreaker.Amplification{Write: 4, Read: 9},
+			},
+		},
+		"PlanBuckets window equals granularity": {
+			request: Request{
+				window:           time.Hour,
+				maxKeysPerLookup: 2,
+				minGranularity:   time.Hour,
+			},
+			response: Response{
+				names:         []string{"1h"},
+				amplification: circuitbreaker.Amplification{Write: 1, Read: 2},
+			},
+		},
+		"PlanBuckets window not a multiple of granularity": {
+			request: Request{
+				window:           90 * time.Second,
+				maxKeysPerLookup: 10,
+				minGranularity:   time.Minute,
+			},
+			response: Response{},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			buckets := circuitbreaker.PlanBuckets(tc.request.window, tc.request.maxKeysPerLookup, tc.request.minGranularity)
+			if tc.response.names == nil {
+				assert.Nil(t, buckets)
+				return
+			}
+
+			names := []string{}
+			for _, bucket := range buckets {
+				names = append(names, bucket.Name)
+			}
+			assert.Equal(t, tc.response.names, names)
+			assert.Equal(t, tc.response.amplification, circuitbreaker.EstimateAmplification(tc.request.window, buckets))
+		})
+	}
+}
+
+func TestPlanner_PlanBucketsWorstCase(t *testing.T) {
+	testcases := map[string]struct {
+		window  time.Duration
+		buckets []*circuitbreaker.Bucket
+	}{
+		"Planned one day window": {
+			window:  24 * time.Hour,
+			buckets: circuitbreaker.PlanBuckets(24*time.Hour, 25, time.Minute),
+		},
+		"Planned one week window": {
+			window:  7 * 24 * time.Hour,
+			buckets: circuitbreaker.PlanBuckets(7*24*time.Hour, 30, time.Minute),
+		},
+		"Default buckets": {
+			window:  24 * time.Hour,
+			buckets: circuitbreaker.DefaultBucket,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mocks := fixture.NewCircuitBreakerMock(ctrl)
+			cb, err := circuitbreaker.New(
+				"test",
+				circuitbreaker.WithCache(mocks.Cache),
+				circuitbreaker.WithBuckets(tc.buckets...),
+				circuitbreaker.WithWindow(tc.window),
+			)
+			assert.Nil(t, err)
+
+			smallest := tc.buckets[len(tc.buckets)-1].Duration
+			start := time.Date(2023, time.May, 12, 0, 0, 0, 0, time.UTC)
+			worst := 0
+			for offset := time.Duration(0); offset < 24*time.Hour; offset += smallest {
+				if keys := cb.GenerateKeys(start.Add(offset)); len(keys) > worst {
+					worst = len(keys)
+				}
+			}
+
+			assert.Equal(t, circuitbreaker.EstimateAmplification(tc.window, tc.buckets).Read, worst)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package circuitbreaker
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	seriesSuccess = "success"
+	seriesFailure = "failure"
+)
+
+// RatioConfig trips the circuit on failure rate instead of the window value
+// FailureRateThreshold is between 0 and 1, zero disables ratio mode
+// MinimumVolume is how many calls the window needs before the failure rate is trusted
+type RatioConfig struct {
+	FailureRateThreshold float64
+	MinimumVolume        int
+}
+
+// GetFailureRate returns failures / (successes + failures) within window duration and the number of calls
+func (c *circuitBreaker) GetFailureRate(ctx context.Context) (float64, int, error) {
+	if !c.Active {
+		return 0, 0, nil
+	}
+
+	values, err := c.calculateSeriesValues(ctx, []string{seriesSuccess, seriesFailure})
+	if err != nil {
+		return 0, 0, err
+	}
+	successes, failures := values[0], values[1]
+
+	volume := saturatingAdd(successes, failures)
+	if volume == 0 {
+		return 0, 0, nil
+	}
+
+	return float64(failures) / float64(volume), int(volume), nil
+}
+
+// RecordFailure records one failed call and reports it to the state machine
+// in ratio mode the circuit is opened once the failure rate reaches the threshold with at least the minimum volume
+func (c *circuitBreaker) RecordFailure(ctx context.Context) error {
+	if !c.Active {
+		return nil
+	}
+
+	if err := c.getSeries(seriesFailure).incrementLatestBuckets(ctx, 1); err != nil {
+		return err
+	}
+	if err := c.RecordResult(ctx, false); err != nil {
+		return err
+	}
+	if c.RatioConfig.FailureRateThreshold <= 0 {
+		return nil
+	}
+
+	rate, volume, err := c.GetFailureRate(ctx)
+	if err != nil {
+		return err
+	}
+	if volume < c.RatioConfig.MinimumVolume || rate < c.RatioConfig.FailureRateThreshold {
+		return nil
+	}
+
+	return c.openIfClosed(ctx)
+}
+
+// RecordSuccess records one successful call and reports it to the state machine
+func (c *circuitBreaker) RecordSuccess(ctx context.Context) error {
+	if !c.Active {
+		return nil
+	}
+
+	if err := c.getSeries(seriesSuccess).incrementLatestBuckets(ctx, 1); err != nil {
+		return err
+	}
+
+	return c.RecordResult(ctx, true)
+}
+
+// SetRatioConfig sets failure rate threshold and minimum volume of ratio mode
+func (c *circuitBreaker) SetRatioConfig(config RatioConfig) {
+	c.RatioConfig = config
+}
+
+// getSeries returns a copy of the circuit breaker whose keys belong to a parallel series
+// it shares buckets, window and lookup strategy, the series is a key segment of its own after the feature name,
+// so the series of one feature never shares keys with another feature such as loan_disbursement_failure
+// example: cb-loan_disbursement-failure-24h-1m-20230510123000
+func (c *circuitBreaker) getSeries(name string) *circuitBreaker {
+	series := *c
+	series.Series = name
+
+	return &series
+}
+
+// calculateSeriesValues calculates the window value of every series with a single cache lookup
+func (c *circuitBreaker) calculateSeriesValues(ctx context.Context, names []string) ([]int64, error) {
+	currentTime := c.Clock.Now().UTC()
+
+	keys := make([][]string, 0, len(names))
+	allKeys := []string{}
+	for _, name := range names {
+		seriesKeys := c.getSeries(name).GenerateKeys(currentTime)
+		keys = append(keys, seriesKeys)
+		allKeys = append(allKeys, seriesKeys...)
+	}
+
+	cacheValues, err := c.Cache.GetInts(ctx, allKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]int64, len(names))
+	for i, seriesKeys := range keys {
+		values[i] = c.sumWindowValues(cacheValues, seriesKeys, currentTime)
+	}
+
+	return values, nil
+}
+
+// sumWindowValues sums cache values of keys generated by GenerateKeys, weighting the previous window of LookupSliding
+// the sum saturates instead of overflowing
+func (c *circuitBreaker) sumWindowValues(cacheValues map[string]int64, keys []string, currentTime time.Time) int64 {
+	if c.LookupStrategy == LookupSliding {
+		_, _, elapsed := c.getSlidingKeys(currentTime)
+		weight := float64(c.WindowDuration-elapsed) / float64(c.WindowDuration)
+		return saturatingAdd(cacheValues[keys[0]], saturatingFloat(float64(cacheValues[keys[1]])*weight))
+	}
+
+	totalValue := int64(0)
+	for _, key := range keys {
+		totalValue = saturatingAdd(totalValue, cacheValues[key])
+	}
+
+	return totalValue
+}
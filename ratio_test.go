@@ -0,0 +1,208 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/cbtest"
+)
+
+func TestCircuitBreaker_FailureRatio(t *testing.T) {
+	type Request struct {
+		config    circuitbreaker.RatioConfig
+		successes int
+		failures  int
+	}
+
+	type Response struct {
+		rate   float64
+		volume int
+		state  circuitbreaker.State
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Failure rate reaches threshold trips": {
+			request: Request{
+				config:    circuitbreaker.RatioConfig{FailureRateThreshold: 0.5, MinimumVolume: 20},
+				successes: 10,
+				failures:  10,
+			},
+			response: Response{
+				rate:   0.5,
+				volume: 20,
+				state:  circuitbreaker.StateOpen,
+			},
+		},
+		"Failure rate below threshold stays closed": {
+			request: Request{
+				config:    circuitbreaker.RatioConfig{FailureRateThreshold: 0.5, MinimumVolume: 20},
+				successes: 15,
+				failures:  5,
+			},
+			response: Response{
+				rate:   0.25,
+				volume: 20,
+				state:  circuitbreaker.StateClosed,
+			},
+		},
+		"Below minimum volume stays closed": {
+			request: Request{
+				config:   circuitbreaker.RatioConfig{FailureRateThreshold: 0.5, MinimumVolume: 20},
+				failures: 19,
+			},
+			response: Response{
+				rate:   1,
+				volume: 19,
+				state:  circuitbreaker.StateClosed,
+			},
+		},
+		"Ratio mode disabled only counts": {
+			request: Request{
+				failures: 30,
+			},
+			response: Response{
+				rate:   1,
+				volume: 30,
+				state:  circuitbreaker.StateClosed,
+			},
+		},
+		"No calls": {
+			request: Request{
+				config: circuitbreaker.RatioConfig{FailureRateThreshold: 0.5, MinimumVolume: 20},
+			},
+			response: Response{
+				state: circuitbreaker.StateClosed,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, client := newRedisClient(t)
+			cb, _ := newTestCircuitBreaker(
+				t,
+				client,
+				"test",
+				circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Minute)),
+				circuitbreaker.WithRatio(tc.request.config),
+				circuitbreaker.WithWindow(5*time.Minute),
+			)
+
+			for i := 0; i < tc.request.successes; i++ {
+				assert.Nil(t, cb.RecordSuccess(ctx))
+			}
+			for i := 0; i < tc.request.failures; i++ {
+				assert.Nil(t, cb.RecordFailure(ctx))
+			}
+
+			rate, volume, err := cb.GetFailureRate(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.rate, rate)
+			assert.Equal(t, tc.response.volume, volume)
+
+			state, err := cb.GetState(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.state, state)
+		})
+	}
+}
+
+func TestCircuitBreaker_FailureRatioWindow(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	cb, clock := newTestCircuitBreaker(
+		t,
+		client,
+		"test",
+		circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Minute)),
+		circuitbreaker.WithRatio(circuitbreaker.RatioConfig{FailureRateThreshold: 0.5, MinimumVolume: 4}),
+		circuitbreaker.WithWindow(5*time.Minute),
+	)
+
+	assert.Nil(t, cb.RecordFailure(ctx))
+	assert.Nil(t, cb.RecordFailure(ctx))
+	assert.Nil(t, cb.RecordFailure(ctx))
+
+	// failures leave the window before the fourth call arrives
+	clock.Advance(10 * time.Minute)
+	assert.Nil(t, cb.RecordFailure(ctx))
+
+	rate, volume, err := cb.GetFailureRate(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1.0, rate)
+	assert.Equal(t, 1, volume)
+
+	state, err := cb.GetState(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.StateClosed, state)
+}
+
+func TestCircuitBreaker_FailureRatioExecute(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	cb, _ := newTestCircuitBreaker(
+		t,
+		client,
+		"test",
+		circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Minute)),
+		circuitbreaker.WithRatio(circuitbreaker.RatioConfig{FailureRateThreshold: 0.5, MinimumVolume: 4}),
+		circuitbreaker.WithWindow(5*time.Minute),
+	)
+
+	assert.Nil(t, cb.Execute(ctx, 1, func(ctx context.Context) error { return nil }))
+	assert.Nil(t, cb.Execute(ctx, 1, func(ctx context.Context) error { return nil }))
+	assert.ErrorIs(t, cb.Execute(ctx, 1, func(ctx context.Context) error { return ErrDownstream }), ErrDownstream)
+	assert.ErrorIs(t, cb.Execute(ctx, 1, func(ctx context.Context) error { return ErrDownstream }), ErrDownstream)
+	assert.ErrorIs(t, cb.Execute(ctx, 1, func(ctx context.Context) error { return nil }), circuitbreaker.ErrCircuitOpen)
+
+	rate, volume, err := cb.GetFailureRate(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.5, rate)
+	assert.Equal(t, 4, volume)
+}
+
+func TestCircuitBreaker_FailureRatioSeriesKeys(t *testing.T) {
+	ctx := context.Background()
+	server, client := newRedisClient(t)
+	clock := cbtest.NewClock(time.Date(2023, time.May, 12, 10, 0, 0, 0, time.UTC))
+	newBreaker := func(featureName string) circuitbreaker.CircuitBreaker {
+		cb, err := circuitbreaker.New(
+			featureName,
+			circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Minute)),
+			circuitbreaker.WithCache(circuitbreaker.NewRedisCache(client, time.Hour)),
+			circuitbreaker.WithCacheTTL(time.Hour),
+			circuitbreaker.WithClock(clock),
+			circuitbreaker.WithRatio(circuitbreaker.RatioConfig{FailureRateThreshold: 0.5, MinimumVolume: 10}),
+			circuitbreaker.WithWindow(5*time.Minute),
+		)
+		assert.Nil(t, err)
+
+		return cb
+	}
+
+	loan := newBreaker("loan")
+	loanSuccess := newBreaker("loan_success")
+	assert.Nil(t, loan.RecordSuccess(ctx))
+	assert.Nil(t, loan.RecordFailure(ctx))
+
+	// series of loan never share keys with the loan_success feature
+	assert.ElementsMatch(t, []string{
+		"cb-loan-failure-5m-1m-20230512100000",
+		"cb-loan-success-5m-1m-20230512100000",
+	}, server.Keys())
+	windowValue, err := loanSuccess.CalculateWindowValue(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), windowValue)
+	rate, volume, err := loanSuccess.GetFailureRate(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(0), rate)
+	assert.Equal(t, 0, volume)
+}
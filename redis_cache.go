@@ -0,0 +1,265 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// incrementIfBelowScript sums the first ARGV[4] keys as the window value, then increments
+// the remaining keys only when window value + amount stays below threshold
+// lua numbers are doubles, so every int64 is split at 10^9 into two exact halves, which keeps sums and comparisons
+// exact over the whole int64 range. Every bucket is checked for overflow before the first INCRBY, so nothing is
+// written when one of them would overflow. The window value is returned as a string, saturated to int64
+var incrementIfBelowScript = redis.NewScript(`
+local base = 1000000000
+local maxInt64 = {9223372036, 854775807}
+local minInt64 = {-9223372037, 145224192}
+
+-- parse splits an integer string into {high, low}, value = high * base + low with 0 <= low < base
+local function parse(value)
+	if not string.match(value, "^%-?%d+$") then
+		error("value is not an integer")
+	end
+	local negative = string.sub(value, 1, 1) == "-"
+	if negative then
+		value = string.sub(value, 2)
+	end
+	local high = tonumber(string.sub(value, 1, -10)) or 0
+	local low = tonumber(string.sub(value, -9))
+	if not negative then
+		return {high, low}
+	end
+	if low > 0 then
+		return {-high - 1, base - low}
+	end
+	return {-high, 0}
+end
+
+local function add(a, b)
+	local low = a[2] + b[2]
+	if low >= base then
+		return {a[1] + b[1] + 1, low - base}
+	end
+	return {a[1] + b[1], low}
+end
+
+local function less(a, b)
+	return a[1] < b[1] or (a[1] == b[1] and a[2] < b[2])
+end
+
+local function format(a)
+	if less(a, minInt64) then
+		a = minInt64
+	elseif less(maxInt64, a) then
+		a = maxInt64
+	end
+
+	local sign = ""
+	if a[1] < 0 then
+		sign = "-"
+		if a[2] > 0 then
+			a = {-a[1] - 1, base - a[2]}
+		else
+			a = {-a[1], 0}
+		end
+	end
+	if a[1] == 0 then
+		return sign .. string.format("%d", a[2])
+	end
+	return sign .. string.format("%d%09d", a[1], a[2])
+end
+
+local amount = parse(ARGV[1])
+local threshold = parse(ARGV[2])
+local ttl = tonumber(ARGV[3])
+local windowKeysCount = tonumber(ARGV[4])
+
+local total = {0, 0}
+for i = 1, windowKeysCount do
+	local value = redis.call("GET", KEYS[i])
+	if value then
+		total = add(total, parse(value))
+	end
+end
+
+if not less(add(total, amount), threshold) then
+	return {0, format(total)}
+end
+
+for i = windowKeysCount + 1, #KEYS do
+	local value = redis.call("GET", KEYS[i])
+	local incremented = amount
+	if value then
+		incremented = add(parse(value), amount)
+	end
+	if less(incremented, minInt64) or less(maxInt64, incremented) then
+		return redis.error_reply("ERR increment or decrement would overflow")
+	end
+end
+
+for i = windowKeysCount + 1, #KEYS do
+	redis.call("INCRBY", KEYS[i], ARGV[1])
+	if ttl > 0 then
+		redis.call("PEXPIRE", KEYS[i], ttl)
+	end
+end
+
+return {1, format(add(total, amount))}
+`)
+
+type redisCache struct {
+	Client             redis.UniversalClient
+	ExpirationDuration time.Duration
+}
+
+// NewRedisCache creates Cache backed by redis, so every instance sharing the same redis sees the same buckets
+// Redis Cluster is not supported out of the box, MGET and the TryConsume / Reserve script touch several keys of a
+// circuit breaker, which must share a slot, wrap the feature name in a hash tag for that, e.g. {loan_disbursement}
+func NewRedisCache(
+	client redis.UniversalClient,
+	expirationDuration time.Duration,
+) Cache {
+	return &redisCache{
+		Client:             client,
+		ExpirationDuration: expirationDuration,
+	}
+}
+
+// GetBool parses the "1" / "0" redis stores for bool
+func (c *redisCache) GetBool(ctx context.Context, key string) (bool, error) {
+	object, err := c.GetString(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	value, err := strconv.ParseBool(object)
+	if err != nil {
+		return false, fmt.Errorf("%w: %q", ErrInvalidCacheValue, object)
+	}
+
+	return value, nil
+}
+
+// GetInts fetches all keys with a single MGET, missing keys are left out of the result
+func (c *redisCache) GetInts(ctx context.Context, keys []string) (map[string]int64, error) {
+	result := make(map[string]int64)
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	objects, err := c.Client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, object := range objects {
+		str, ok := object.(string)
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCacheValue, str)
+		}
+		result[keys[i]] = value
+	}
+	return result, nil
+}
+
+func (c *redisCache) GetString(ctx context.Context, key string) (string, error) {
+	object, err := c.Client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrCacheMiss
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return object, nil
+}
+
+// IncrementInt increments key with INCRBY and refreshes its expiration with EXPIRE in one transaction
+// without ttl and ExpirationDuration the key never expires, EXPIRE 0 would delete it right away
+// redis refuses an INCRBY that would overflow and leaves key unchanged, it is reported as ErrAmountOverflow
+func (c *redisCache) IncrementInt(ctx context.Context, key string, val int64, ttl time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := c.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.IncrBy(ctx, key, val)
+		if expiration := c.getTTL(ttl); expiration > 0 {
+			pipe.Expire(ctx, key, expiration)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, toOverflowError(err, key)
+	}
+
+	return incr.Val(), nil
+}
+
+// IncrementIntIfBelow runs check and increment as a single lua script, so it is atomic across every instance
+func (c *redisCache) IncrementIntIfBelow(ctx context.Context, windowKeys []string, bucketKeys []string, val int64, threshold int64, ttl time.Duration) (bool, int64, error) {
+	keys := make([]string, 0, len(windowKeys)+len(bucketKeys))
+	keys = append(keys, windowKeys...)
+	keys = append(keys, bucketKeys...)
+
+	result, err := incrementIfBelowScript.Run(
+		ctx,
+		c.Client,
+		keys,
+		strconv.FormatInt(val, 10),
+		strconv.FormatInt(threshold, 10),
+		c.getTTL(ttl).Milliseconds(),
+		len(windowKeys),
+	).Slice()
+	if err != nil {
+		return false, 0, toOverflowError(err, keys...)
+	}
+	if len(result) != 2 {
+		return false, 0, ErrInvalidCacheValue
+	}
+
+	allowed, isInt := result[0].(int64)
+	windowValueStr, isString := result[1].(string)
+	if !isInt || !isString {
+		return false, 0, ErrInvalidCacheValue
+	}
+	windowValue, err := strconv.ParseInt(windowValueStr, 10, 64)
+	if err != nil {
+		return false, 0, ErrInvalidCacheValue
+	}
+
+	return allowed == 1, windowValue, nil
+}
+
+func (c *redisCache) SetBool(ctx context.Context, key string, value bool, ttl time.Duration) error {
+	return c.Client.Set(ctx, key, value, c.getTTL(ttl)).Err()
+}
+
+func (c *redisCache) SetString(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return c.Client.Set(ctx, key, value, c.getTTL(ttl)).Err()
+}
+
+// toOverflowError wraps ErrAmountOverflow when redis refused to increment past int64
+// example: ERR increment or decrement would overflow
+func toOverflowError(err error, keys ...string) error {
+	if !strings.Contains(err.Error(), "would overflow") {
+		return err
+	}
+
+	return fmt.Errorf("%w: %s", ErrAmountOverflow, strings.Join(keys, ", "))
+}
+
+// getTTL falls back to ExpirationDuration when ttl is not set
+func (c *redisCache) getTTL(ttl time.Duration) time.Duration {
+	if ttl > 0 {
+		return ttl
+	}
+	return c.ExpirationDuration
+}
@@ -0,0 +1,544 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/cbtest"
+)
+
+func newRedisClient(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+// newTestCircuitBreaker creates circuit breaker featureName on client with 1h and 1m buckets, a 24h window and a manual clock
+// opts are applied after these defaults, so they replace them
+func newTestCircuitBreaker(t *testing.T, client redis.UniversalClient, featureName string, opts ...circuitbreaker.Option) (circuitbreaker.CircuitBreaker, *cbtest.Clock) {
+	t.Helper()
+
+	clock := cbtest.NewClock(time.Date(2023, time.May, 9, 10, 42, 0, 0, time.UTC))
+	defaults := []circuitbreaker.Option{
+		circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Hour), circuitbreaker.NewBucket(time.Minute)),
+		circuitbreaker.WithCache(circuitbreaker.NewRedisCache(client, 5*time.Minute)),
+		circuitbreaker.WithCacheTTL(28 * time.Hour),
+		circuitbreaker.WithClock(clock),
+	}
+
+	cb, err := circuitbreaker.New(featureName, append(defaults, opts...)...)
+	assert.Nil(t, err)
+
+	return cb, clock
+}
+
+func TestRedisCache_NewRedisCache(t *testing.T) {
+	_, client := newRedisClient(t)
+
+	cache := circuitbreaker.NewRedisCache(client, 5*time.Minute)
+
+	res := reflect.TypeOf(cache).String()
+	assert.Equal(t, res, "*circuitbreaker.redisCache")
+}
+
+func TestRedisCache_GetBool(t *testing.T) {
+	type Request struct {
+		key string
+	}
+	type Response struct {
+		result bool
+		err    error
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+		preFunc  func(s *miniredis.Miniredis, req Request)
+	}{
+		"GetBool success": {
+			request: Request{
+				key: "test-key",
+			},
+			response: Response{
+				result: true,
+			},
+			preFunc: func(s *miniredis.Miniredis, req Request) {
+				s.Set(req.key, "1")
+			},
+		},
+		"key not exist": {
+			request: Request{
+				key: "test-key",
+			},
+			response: Response{
+				err: circuitbreaker.ErrCacheMiss,
+			},
+			preFunc: func(s *miniredis.Miniredis, req Request) {},
+		},
+		"invalid value": {
+			request: Request{
+				key: "test-key",
+			},
+			response: Response{
+				err: circuitbreaker.ErrInvalidCacheValue,
+			},
+			preFunc: func(s *miniredis.Miniredis, req Request) {
+				s.Set(req.key, "open:1683628920000")
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			server, client := newRedisClient(t)
+			tc.preFunc(server, tc.request)
+
+			cache := circuitbreaker.NewRedisCache(client, 5*time.Minute)
+			result, err := cache.GetBool(context.Background(), tc.request.key)
+			assert.ErrorIs(t, err, tc.response.err)
+			assert.Equal(t, tc.response.result, result)
+		})
+	}
+}
+
+func TestRedisCache_GetString(t *testing.T) {
+	type Request struct {
+		key string
+	}
+	type Response struct {
+		result string
+		err    error
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+		preFunc  func(s *miniredis.Miniredis, req Request)
+	}{
+		"GetString success": {
+			request: Request{
+				key: "test-key",
+			},
+			response: Response{
+				result: "open:1683628920000",
+			},
+			preFunc: func(s *miniredis.Miniredis, req Request) {
+				s.Set(req.key, "open:1683628920000")
+			},
+		},
+		"key not exist": {
+			request: Request{
+				key: "test-key",
+			},
+			response: Response{
+				err: circuitbreaker.ErrCacheMiss,
+			},
+			preFunc: func(s *miniredis.Miniredis, req Request) {},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			server, client := newRedisClient(t)
+			tc.preFunc(server, tc.request)
+
+			cache := circuitbreaker.NewRedisCache(client, 5*time.Minute)
+			result, err := cache.GetString(context.Background(), tc.request.key)
+			assert.Equal(t, tc.response.err, err)
+			assert.Equal(t, tc.response.result, result)
+		})
+	}
+}
+
+func TestRedisCache_Set(t *testing.T) {
+	type Request struct {
+		key string
+		set func(ctx context.Context, cache circuitbreaker.Cache, key string, ttl time.Duration) error
+		ttl time.Duration
+	}
+	type Response struct {
+		value string
+		ttl   time.Duration
+	}
+
+	setBool := func(ctx context.Context, cache circuitbreaker.Cache, key string, ttl time.Duration) error {
+		return cache.SetBool(ctx, key, true, ttl)
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"SetBool success": {
+			request: Request{
+				key: "test-key",
+				set: setBool,
+				ttl: time.Minute,
+			},
+			response: Response{
+				value: "1",
+				ttl:   time.Minute,
+			},
+		},
+		"ttl is zero will use expiration duration": {
+			request: Request{
+				key: "test-key",
+				set: setBool,
+				ttl: 0,
+			},
+			response: Response{
+				value: "1",
+				ttl:   5 * time.Minute,
+			},
+		},
+		"SetString success": {
+			request: Request{
+				key: "test-key",
+				set: func(ctx context.Context, cache circuitbreaker.Cache, key string, ttl time.Duration) error {
+					return cache.SetString(ctx, key, "closed:1683628920000", ttl)
+				},
+				ttl: time.Minute,
+			},
+			response: Response{
+				value: "closed:1683628920000",
+				ttl:   time.Minute,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			server, client := newRedisClient(t)
+
+			cache := circuitbreaker.NewRedisCache(client, 5*time.Minute)
+			err := tc.request.set(context.Background(), cache, tc.request.key, tc.request.ttl)
+			assert.Nil(t, err)
+
+			value, _ := server.Get(tc.request.key)
+			assert.Equal(t, tc.response.value, value)
+			assert.Equal(t, tc.response.ttl, server.TTL(tc.request.key))
+		})
+	}
+}
+
+func TestRedisCache_GetInts(t *testing.T) {
+	type Request struct {
+		keys []string
+	}
+	type Response struct {
+		result interface{}
+		err    bool
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+		preFunc  func(s *miniredis.Miniredis)
+	}{
+		"GetInts success": {
+			request: Request{
+				keys: []string{"test-key-1", "test-key-2", "test-key-3"},
+			},
+			response: Response{
+				result: map[string]int64{"test-key-1": 10, "test-key-3": 30},
+			},
+			preFunc: func(s *miniredis.Miniredis) {
+				s.Set("test-key-1", "10")
+				s.Set("test-key-3", "30")
+			},
+		},
+		"keys not exist": {
+			request: Request{
+				keys: []string{"test-key"},
+			},
+			response: Response{
+				result: map[string]int64{},
+			},
+			preFunc: func(s *miniredis.Miniredis) {},
+		},
+		"no keys": {
+			request: Request{
+				keys: []string{},
+			},
+			response: Response{
+				result: map[string]int64{},
+			},
+			preFunc: func(s *miniredis.Miniredis) {},
+		},
+		"value is not a number": {
+			request: Request{
+				keys: []string{"test-key"},
+			},
+			response: Response{
+				result: nil,
+				err:    true,
+			},
+			preFunc: func(s *miniredis.Miniredis) {
+				s.Set("test-key", "abc")
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			server, client := newRedisClient(t)
+			tc.preFunc(server)
+
+			cache := circuitbreaker.NewRedisCache(client, 5*time.Minute)
+			result, err := cache.GetInts(context.Background(), tc.request.keys)
+			assert.Equal(t, tc.response.err, err != nil)
+			if err == nil {
+				assert.Equal(t, tc.response.result, result)
+			}
+		})
+	}
+}
+
+func TestRedisCache_IncrementInt(t *testing.T) {
+	type Request struct {
+		key string
+		val int64
+		ttl time.Duration
+	}
+	type Response struct {
+		result int64
+		ttl    time.Duration
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+		preFunc  func(s *miniredis.Miniredis, req Request)
+	}{
+		"IncrementInt success": {
+			request: Request{
+				key: "test-key",
+				val: 10,
+				ttl: time.Hour,
+			},
+			response: Response{
+				result: 20,
+				ttl:    time.Hour,
+			},
+			preFunc: func(s *miniredis.Miniredis, req Request) {
+				s.Set(req.key, "10")
+			},
+		},
+		"key not exist will be created": {
+			request: Request{
+				key: "test-key",
+				val: 10,
+				ttl: 0,
+			},
+			response: Response{
+				result: 10,
+				ttl:    5 * time.Minute,
+			},
+			preFunc: func(s *miniredis.Miniredis, req Request) {},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			server, client := newRedisClient(t)
+			tc.preFunc(server, tc.request)
+
+			cache := circuitbreaker.NewRedisCache(client, 5*time.Minute)
+			result, err := cache.IncrementInt(context.Background(), tc.request.key, tc.request.val, tc.request.ttl)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.result, result)
+			assert.Equal(t, tc.response.ttl, server.TTL(tc.request.key))
+		})
+	}
+}
+
+func TestRedisCache_CircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	server, client := newRedisClient(t)
+
+	cb := circuitbreaker.NewCircuitBreaker(
+		[]*circuitbreaker.Bucket{
+			circuitbreaker.NewBucket(time.Hour),
+			circuitbreaker.NewBucket(time.Minute),
+		},
+		circuitbreaker.NewRedisCache(client, 5*time.Minute),
+		28*time.Hour,
+		"test",
+		24*time.Hour,
+	)
+	cb.SetThreshold(100)
+
+	assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, 30))
+	assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, 30))
+
+	keys := server.Keys()
+	assert.Len(t, keys, 2)
+	for _, key := range keys {
+		assert.Equal(t, 28*time.Hour, server.TTL(key))
+	}
+
+	value, err := cb.CalculateWindowValue(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(60), value)
+
+	assert.Nil(t, cb.UpdateTrip(ctx, true))
+	isTripped, err := cb.GetTrip(ctx)
+	assert.Nil(t, err)
+	assert.True(t, isTripped)
+}
+
+func TestRedisCache_IncrementIntIfBelow(t *testing.T) {
+	t.Run("concurrent callers never reach threshold", func(t *testing.T) {
+		ctx := context.Background()
+		server, client := newRedisClient(t)
+		cache := circuitbreaker.NewRedisCache(client, 5*time.Minute)
+		keys := []string{"test-key-1h", "test-key-1m"}
+
+		var wg sync.WaitGroup
+		var allowedCount int32
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				allowed, _, err := cache.IncrementIntIfBelow(ctx, keys[:1], keys, 1, 50, time.Hour)
+				assert.Nil(t, err)
+				if allowed {
+					atomic.AddInt32(&allowedCount, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(49), allowedCount)
+		result, err := cache.GetInts(ctx, keys)
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]int64{"test-key-1h": 49, "test-key-1m": 49}, result)
+		assert.Equal(t, time.Hour, server.TTL("test-key-1m"))
+	})
+
+	t.Run("rejected amount is not written", func(t *testing.T) {
+		ctx := context.Background()
+		server, client := newRedisClient(t)
+		cache := circuitbreaker.NewRedisCache(client, 5*time.Minute)
+		server.Set("test-key", "60")
+
+		allowed, windowValue, err := cache.IncrementIntIfBelow(ctx, []string{"test-key"}, []string{"test-key"}, 60, 100, time.Minute)
+		assert.Nil(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, int64(60), windowValue)
+
+		value, _ := server.Get("test-key")
+		assert.Equal(t, "60", value)
+	})
+
+	t.Run("window value is compared exactly near int64 limits", func(t *testing.T) {
+		ctx := context.Background()
+		server, client := newRedisClient(t)
+		cache := circuitbreaker.NewRedisCache(client, 5*time.Minute)
+		server.Set("test-key-1h", "9223372036854775797")
+
+		allowed, windowValue, err := cache.IncrementIntIfBelow(ctx, []string{"test-key-1h"}, []string{"test-key-1m"}, 9, math.MaxInt64, time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, int64(math.MaxInt64-1), windowValue)
+
+		allowed, windowValue, err = cache.IncrementIntIfBelow(ctx, []string{"test-key-1h"}, []string{"test-key-1m"}, 10, math.MaxInt64, time.Minute)
+		assert.Nil(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, int64(math.MaxInt64-10), windowValue)
+
+		server.Set("test-key-1h", "-9223372036854775800")
+		allowed, windowValue, err = cache.IncrementIntIfBelow(ctx, []string{"test-key-1h"}, []string{"test-key-1m"}, 1, 0, time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, int64(-9223372036854775799), windowValue)
+	})
+
+	t.Run("overflowing bucket leaves every bucket unchanged", func(t *testing.T) {
+		ctx := context.Background()
+		server, client := newRedisClient(t)
+		cache := circuitbreaker.NewRedisCache(client, 5*time.Minute)
+		server.Set("test-key-1h", "0")
+		server.Set("test-key-1m", "9223372036854775806")
+
+		allowed, _, err := cache.IncrementIntIfBelow(ctx, []string{"test-key-1h"}, []string{"test-key-1h", "test-key-1m"}, 5, math.MaxInt64, time.Minute)
+		assert.ErrorIs(t, err, circuitbreaker.ErrAmountOverflow)
+		assert.False(t, allowed)
+
+		value, _ := server.Get("test-key-1h")
+		assert.Equal(t, "0", value)
+		value, _ = server.Get("test-key-1m")
+		assert.Equal(t, "9223372036854775806", value)
+	})
+}
+
+func TestRedisCache_ZeroExpiration(t *testing.T) {
+	ctx := context.Background()
+	server, client := newRedisClient(t)
+
+	// neither cache expiration nor cache ttl is set, keys must live instead of being expired right away
+	cb := circuitbreaker.NewCircuitBreaker(
+		[]*circuitbreaker.Bucket{
+			circuitbreaker.NewBucket(time.Hour),
+			circuitbreaker.NewBucket(time.Minute),
+		},
+		circuitbreaker.NewRedisCache(client, 0),
+		0,
+		"test",
+		24*time.Hour,
+	)
+	cb.SetThreshold(100)
+
+	assert.Nil(t, cb.UpdateLatestBucketsValue(ctx, 10))
+	allowed, windowValue, err := cb.TryConsume(ctx, 80)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, int64(90), windowValue)
+
+	allowed, _, err = cb.TryConsume(ctx, 10)
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+
+	keys := server.Keys()
+	assert.Len(t, keys, 2)
+	for _, key := range keys {
+		assert.Equal(t, time.Duration(0), server.TTL(key))
+	}
+}
+
+func TestRedisCache_HashTaggedFeatureName(t *testing.T) {
+	ctx := context.Background()
+	server, client := newRedisClient(t)
+
+	cb, err := circuitbreaker.New(
+		"{loan_disbursement}",
+		circuitbreaker.WithBuckets(circuitbreaker.NewBucket(time.Hour), circuitbreaker.NewBucket(time.Minute)),
+		circuitbreaker.WithCache(circuitbreaker.NewRedisCache(client, 5*time.Minute)),
+		circuitbreaker.WithCacheTTL(28*time.Hour),
+		circuitbreaker.WithThreshold(100),
+	)
+	assert.Nil(t, err)
+
+	allowed, _, err := cb.TryConsume(ctx, 10)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+	assert.Nil(t, cb.UpdateTrip(ctx, true))
+
+	// every key hashes on the same tag, so a cluster keeps them in one slot
+	keys := server.Keys()
+	assert.Len(t, keys, 3)
+	for _, key := range keys {
+		assert.Contains(t, key, "-{loan_disbursement}-")
+	}
+}
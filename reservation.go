@@ -0,0 +1,172 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	DefaultReservationTimeout = 5 * time.Minute
+
+	ErrReservationClosed = errors.New("reservation already committed or released")
+	ErrThresholdExceeded = errors.New("threshold exceeded")
+)
+
+type Reservation interface {
+	Amount() int64
+	Commit(ctx context.Context) error
+	Release(ctx context.Context) error
+}
+
+type reservationState int
+
+const (
+	reservationPending reservationState = iota
+	reservationCommitted
+	reservationReleased
+)
+
+type reservation struct {
+	circuitBreaker *circuitBreaker
+
+	amount int64
+	keys   []string
+	state  reservationState
+	timer  Timer
+	mutex  sync.Mutex
+}
+
+// Reserve counts amount toward the window right away, until it is committed or released.
+// Reservation not committed within ReservationTimeout is released automatically,
+// the timeout runs on Clock when it is TimerClock and on the system timer otherwise
+func (c *circuitBreaker) Reserve(ctx context.Context, amount int64) (Reservation, error) {
+	if !c.Active {
+		return &reservation{circuitBreaker: c, amount: amount}, nil
+	}
+
+	threshold, err := c.getThreshold(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := c.Clock.Now().UTC()
+	keys := c.getLatestBucketKeys(now)
+	allowed, _, err := c.incrementIfBelow(ctx, now, keys, amount, threshold)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrThresholdExceeded
+	}
+
+	r := &reservation{
+		circuitBreaker: c,
+		amount:         amount,
+		keys:           keys,
+	}
+	if c.ReservationTimeout > 0 {
+		r.mutex.Lock()
+		r.timer = afterFunc(c.Clock, c.ReservationTimeout, r.autoRelease)
+		r.mutex.Unlock()
+	}
+
+	return r, nil
+}
+
+// SetReservationTimeout sets how long a reservation lives before it is released automatically
+// zero timeout disables auto release
+func (c *circuitBreaker) SetReservationTimeout(timeout time.Duration) {
+	c.ReservationTimeout = timeout
+}
+
+// Amount returns reserved amount
+func (r *reservation) Amount() int64 {
+	return r.amount
+}
+
+// Commit keeps reserved amount in the window
+func (r *reservation) Commit(ctx context.Context) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.state != reservationPending {
+		return ErrReservationClosed
+	}
+	r.stopTimer()
+	r.state = reservationCommitted
+
+	return nil
+}
+
+// Release subtracts reserved amount from the same bucket keys it was written to
+// the auto release timer is only stopped once every key is released, so a failed release is still retried by it
+func (r *reservation) Release(ctx context.Context) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.state != reservationPending {
+		return ErrReservationClosed
+	}
+
+	for i, key := range r.keys {
+		_, err := r.circuitBreaker.Cache.IncrementInt(ctx, key, -r.amount, r.circuitBreaker.CacheTTL)
+		if err != nil {
+			// keep the keys that weren't released yet, so release can be retried
+			r.keys = r.keys[i:]
+			return err
+		}
+	}
+	r.stopTimer()
+	r.state = reservationReleased
+
+	return nil
+}
+
+// autoRelease releases the reservation once ReservationTimeout is reached, a failed release is retried
+// after another ReservationTimeout, so the amount doesn't stay counted until its keys expire
+func (r *reservation) autoRelease() {
+	if err := r.Release(context.Background()); err == nil || errors.Is(err, ErrReservationClosed) {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.state == reservationPending {
+		r.timer = afterFunc(r.circuitBreaker.Clock, r.circuitBreaker.ReservationTimeout, r.autoRelease)
+	}
+}
+
+// rollback takes back the amount of a committed reservation, e.g. when another reservation of Composite can't be committed
+func (r *reservation) rollback(ctx context.Context) error {
+	r.mutex.Lock()
+	if r.state != reservationCommitted {
+		r.mutex.Unlock()
+		return ErrReservationClosed
+	}
+	r.state = reservationPending
+	r.mutex.Unlock()
+
+	return r.Release(ctx)
+}
+
+// rollback takes back a committed reservation of this package, reservations it doesn't know can't be rolled back
+func rollback(ctx context.Context, r Reservation) error {
+	rollbacker, ok := r.(interface {
+		rollback(ctx context.Context) error
+	})
+	if !ok {
+		return fmt.Errorf("%w: %T can't be rolled back", ErrReservationClosed, r)
+	}
+
+	return rollbacker.rollback(ctx)
+}
+
+func (r *reservation) stopTimer() {
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+}
@@ -0,0 +1,208 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/cbtest"
+	"go-circuit-breaker/fixture"
+)
+
+func TestCircuitBreaker_Reserve(t *testing.T) {
+	type Request struct {
+		amount int64
+		action func(ctx context.Context, r circuitbreaker.Reservation) error
+	}
+
+	type Response struct {
+		windowValue int64
+		err         error
+		actionErr   error
+	}
+
+	testcases := map[string]struct {
+		request  Request
+		response Response
+	}{
+		"Reservation counts toward window value": {
+			request: Request{
+				amount: 30,
+				action: func(ctx context.Context, r circuitbreaker.Reservation) error { return nil },
+			},
+			response: Response{
+				windowValue: 30,
+			},
+		},
+		"Commit keeps the amount": {
+			request: Request{
+				amount: 30,
+				action: func(ctx context.Context, r circuitbreaker.Reservation) error { return r.Commit(ctx) },
+			},
+			response: Response{
+				windowValue: 30,
+			},
+		},
+		"Release subtracts the amount": {
+			request: Request{
+				amount: 30,
+				action: func(ctx context.Context, r circuitbreaker.Reservation) error { return r.Release(ctx) },
+			},
+			response: Response{
+				windowValue: 0,
+			},
+		},
+		"Release after commit fails": {
+			request: Request{
+				amount: 30,
+				action: func(ctx context.Context, r circuitbreaker.Reservation) error {
+					r.Commit(ctx)
+					return r.Release(ctx)
+				},
+			},
+			response: Response{
+				windowValue: 30,
+				actionErr:   circuitbreaker.ErrReservationClosed,
+			},
+		},
+		"Reservation exceeding threshold is rejected": {
+			request: Request{
+				amount: 100,
+			},
+			response: Response{
+				windowValue: 0,
+				err:         circuitbreaker.ErrThresholdExceeded,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, client := newRedisClient(t)
+			cb, _ := newTestCircuitBreaker(t, client, "test", circuitbreaker.WithThreshold(100))
+
+			r, err := cb.Reserve(ctx, tc.request.amount)
+			assert.Equal(t, tc.response.err, err)
+			if err == nil {
+				assert.Equal(t, tc.request.amount, r.Amount())
+				assert.Equal(t, tc.response.actionErr, tc.request.action(ctx, r))
+			}
+
+			windowValue, err := cb.CalculateWindowValue(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.windowValue, windowValue)
+		})
+	}
+}
+
+// TestCircuitBreaker_ReserveAutoRelease runs the timeout on the real clock
+func TestCircuitBreaker_ReserveAutoRelease(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	cb, _ := newTestCircuitBreaker(t, client, "test", circuitbreaker.WithClock(circuitbreaker.NewRealClock()), circuitbreaker.WithThreshold(100))
+	cb.SetReservationTimeout(10 * time.Millisecond)
+
+	r, err := cb.Reserve(ctx, 30)
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		windowValue, err := cb.CalculateWindowValue(ctx)
+		return err == nil && windowValue == 0
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, circuitbreaker.ErrReservationClosed, r.Commit(ctx))
+}
+
+func TestCircuitBreaker_ReserveAutoReleaseClock(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	cb, clock := newTestCircuitBreaker(t, client, "test", circuitbreaker.WithThreshold(100))
+	cb.SetReservationTimeout(time.Minute)
+
+	released, err := cb.Reserve(ctx, 30)
+	assert.Nil(t, err)
+	committed, err := cb.Reserve(ctx, 20)
+	assert.Nil(t, err)
+	assert.Nil(t, committed.Commit(ctx))
+
+	// timeout follows the clock, nothing is released before it is reached
+	clock.Advance(time.Minute - time.Second)
+	windowValue, err := cb.CalculateWindowValue(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(50), windowValue)
+
+	clock.Advance(time.Second)
+	windowValue, err = cb.CalculateWindowValue(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(20), windowValue)
+	assert.Equal(t, circuitbreaker.ErrReservationClosed, released.Commit(ctx))
+}
+
+func TestCircuitBreaker_ReserveReleaseError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mocks := fixture.NewCircuitBreakerMock(ctrl)
+	cb := circuitbreaker.NewCircuitBreaker(
+		[]*circuitbreaker.Bucket{
+			circuitbreaker.NewBucket(4 * time.Hour),
+		},
+		mocks.Cache,
+		24*time.Hour,
+		"test",
+		24*time.Hour,
+	)
+	cb.SetReservationTimeout(0)
+
+	mocks.Cache.EXPECT().IncrementIntIfBelow(gomock.Any(), gomock.Any(), gomock.Any(), int64(30), gomock.Any(), gomock.Any()).Return(true, int64(30), nil)
+	r, err := cb.Reserve(ctx, 30)
+	assert.Nil(t, err)
+
+	gomock.InOrder(
+		mocks.Cache.EXPECT().IncrementInt(gomock.Any(), gomock.Any(), int64(-30), gomock.Any()).Return(int64(0), ErrUnexpectedRedis),
+		mocks.Cache.EXPECT().IncrementInt(gomock.Any(), gomock.Any(), int64(-30), gomock.Any()).Return(int64(0), nil),
+	)
+	assert.Equal(t, ErrUnexpectedRedis, r.Release(ctx))
+	assert.Nil(t, r.Release(ctx))
+}
+
+func TestCircuitBreaker_ReserveAutoReleaseRetry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mocks := fixture.NewCircuitBreakerMock(ctrl)
+	clock := cbtest.NewClock(time.Date(2023, time.May, 9, 10, 42, 0, 0, time.UTC))
+	cb := circuitbreaker.NewCircuitBreaker(
+		[]*circuitbreaker.Bucket{
+			circuitbreaker.NewBucket(4 * time.Hour),
+		},
+		mocks.Cache,
+		24*time.Hour,
+		"test",
+		24*time.Hour,
+		circuitbreaker.WithClock(clock),
+	)
+	cb.SetReservationTimeout(time.Minute)
+
+	mocks.Cache.EXPECT().IncrementIntIfBelow(gomock.Any(), gomock.Any(), gomock.Any(), int64(30), gomock.Any(), gomock.Any()).Return(true, int64(30), nil)
+	r, err := cb.Reserve(ctx, 30)
+	assert.Nil(t, err)
+
+	gomock.InOrder(
+		mocks.Cache.EXPECT().IncrementInt(gomock.Any(), gomock.Any(), int64(-30), gomock.Any()).Return(int64(0), ErrUnexpectedRedis),
+		mocks.Cache.EXPECT().IncrementInt(gomock.Any(), gomock.Any(), int64(-30), gomock.Any()).Return(int64(0), ErrUnexpectedRedis),
+		mocks.Cache.EXPECT().IncrementInt(gomock.Any(), gomock.Any(), int64(-30), gomock.Any()).Return(int64(0), nil),
+	)
+	// a failed manual release leaves the timer armed
+	assert.Equal(t, ErrUnexpectedRedis, r.Release(ctx))
+	// a failed auto release is retried after another timeout
+	clock.Advance(time.Minute)
+	clock.Advance(time.Minute)
+	assert.Equal(t, circuitbreaker.ErrReservationClosed, r.Commit(ctx))
+}
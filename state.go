@@ -0,0 +1,269 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	DefaultStateMachineConfig = StateMachineConfig{
+		OpenDuration:             time.Minute,
+		HalfOpenMaxProbes:        1,
+		HalfOpenSuccessThreshold: 1,
+	}
+)
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+var stateNames = map[State]string{
+	StateClosed:   "closed",
+	StateOpen:     "open",
+	StateHalfOpen: "half_open",
+}
+
+func (s State) String() string {
+	return stateNames[s]
+}
+
+// StateMachineConfig controls how circuit breaker moves between states
+// Open -> Half-Open after OpenDuration
+// Half-Open allows HalfOpenMaxProbes calls, closes after HalfOpenSuccessThreshold successes, opens again on any failure
+// or once it has lasted OpenDuration without enough results
+type StateMachineConfig struct {
+	OpenDuration             time.Duration
+	HalfOpenMaxProbes        int
+	HalfOpenSuccessThreshold int
+}
+
+// stateValue is what is persisted under TripKey with format <state>:<since in unix millis>
+// example: open:1683628920000
+type stateValue struct {
+	State State
+	Since time.Time
+}
+
+func (v stateValue) String() string {
+	return fmt.Sprintf("%s:%d", v.State, v.Since.UnixMilli())
+}
+
+func parseStateValue(value string) (stateValue, error) {
+	name, sinceStr, found := strings.Cut(value, ":")
+	if !found {
+		// trip flag written before state machine existed, it has no since so it stays open until closed manually
+		// redis stores bool as "1" / "0"
+		isTripped, err := strconv.ParseBool(value)
+		if err != nil {
+			return stateValue{}, ErrInvalidCacheValue
+		}
+		if isTripped {
+			return stateValue{State: StateOpen}, nil
+		}
+		return stateValue{State: StateClosed}, nil
+	}
+
+	since, err := strconv.ParseInt(sinceStr, 10, 64)
+	if err != nil {
+		return stateValue{}, ErrInvalidCacheValue
+	}
+	for state, stateName := range stateNames {
+		if stateName == name {
+			return stateValue{State: state, Since: time.UnixMilli(since).UTC()}, nil
+		}
+	}
+
+	return stateValue{}, ErrInvalidCacheValue
+}
+
+// GetState retrieves current state from cache, moving Open to Half-Open once OpenDuration has passed
+func (c *circuitBreaker) GetState(ctx context.Context) (State, error) {
+	value, err := c.getStateValue(ctx)
+	if errors.Is(err, ErrCacheMiss) {
+		return StateClosed, nil
+	}
+	if err != nil {
+		return StateClosed, err
+	}
+
+	return value.State, nil
+}
+
+// openIfClosed opens the circuit unless it is already Open or Half-Open
+func (c *circuitBreaker) openIfClosed(ctx context.Context) error {
+	state, err := c.GetState(ctx)
+	if err != nil {
+		return err
+	}
+	if state != StateClosed {
+		return nil
+	}
+
+	return c.setState(ctx, StateOpen, c.Clock.Now().UTC())
+}
+
+// Allow tells whether a call may go through
+// in Half-Open state only HalfOpenMaxProbes calls are allowed, shared among every instance
+func (c *circuitBreaker) Allow(ctx context.Context) (bool, error) {
+	if !c.Active {
+		return true, nil
+	}
+
+	value, err := c.getStateValue(ctx)
+	if errors.Is(err, ErrCacheMiss) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	switch value.State {
+	case StateOpen:
+		return false, nil
+	case StateHalfOpen:
+		probes, err := c.Cache.IncrementInt(ctx, c.getStateCounterKey("probe", value), 1, c.CacheTTL)
+		if err != nil {
+			return false, err
+		}
+		return probes <= int64(c.StateMachineConfig.HalfOpenMaxProbes), nil
+	}
+
+	return true, nil
+}
+
+// ReleaseProbe gives back a Half-Open probe Allow handed out for a call that was never made
+func (c *circuitBreaker) ReleaseProbe(ctx context.Context) error {
+	if !c.Active {
+		return nil
+	}
+
+	value, err := c.getStateValue(ctx)
+	if errors.Is(err, ErrCacheMiss) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if value.State != StateHalfOpen {
+		return nil
+	}
+
+	_, err = c.Cache.IncrementInt(ctx, c.getStateCounterKey("probe", value), -1, c.CacheTTL)
+	return err
+}
+
+// RecordResult reports the outcome of an allowed call
+// in Half-Open state, failure opens the circuit again and enough successes close it
+func (c *circuitBreaker) RecordResult(ctx context.Context, success bool) error {
+	if !c.Active {
+		return nil
+	}
+
+	value, err := c.getStateValue(ctx)
+	if errors.Is(err, ErrCacheMiss) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if value.State != StateHalfOpen {
+		return nil
+	}
+
+	if !success {
+		return c.setState(ctx, StateOpen, c.Clock.Now().UTC())
+	}
+
+	successes, err := c.Cache.IncrementInt(ctx, c.getStateCounterKey("success", value), 1, c.CacheTTL)
+	if err != nil {
+		return err
+	}
+	if successes >= int64(c.StateMachineConfig.HalfOpenSuccessThreshold) {
+		return c.setState(ctx, StateClosed, c.Clock.Now().UTC())
+	}
+
+	return nil
+}
+
+// SetStateMachineConfig sets open duration and half-open probe configuration
+func (c *circuitBreaker) SetStateMachineConfig(config StateMachineConfig) {
+	c.StateMachineConfig = config
+}
+
+// getStateValue reads state from TripKey
+func (c *circuitBreaker) getStateValue(ctx context.Context) (stateValue, error) {
+	object, err := c.Cache.GetString(ctx, c.TripKey)
+	if err != nil {
+		return stateValue{}, err
+	}
+
+	value, err := parseStateValue(object)
+	if err != nil {
+		return stateValue{}, err
+	}
+
+	next, changed := c.nextStateValue(value, c.Clock.Now().UTC())
+	if !changed {
+		return value, nil
+	}
+	if err := c.Cache.SetString(ctx, c.TripKey, next.String(), c.CacheTTL); err != nil {
+		return stateValue{}, err
+	}
+
+	return next, nil
+}
+
+// nextStateValue moves Open to Half-Open once OpenDuration has passed, and Half-Open back to Open once it has lasted
+// OpenDuration without enough results, so a probe that never reports back doesn't hold the circuit Half-Open
+// Open and Half-Open then take turns every OpenDuration, each Half-Open with fresh probe counters
+// every instance computes the same since from the stored one, so they share the same probe counters
+func (c *circuitBreaker) nextStateValue(value stateValue, now time.Time) (stateValue, bool) {
+	openDuration := c.StateMachineConfig.OpenDuration
+	if value.Since.IsZero() || value.State == StateClosed {
+		return value, false
+	}
+
+	halfOpenSince := value.Since
+	if value.State == StateOpen {
+		halfOpenSince = value.Since.Add(openDuration)
+		if now.Before(halfOpenSince) {
+			return value, false
+		}
+	}
+
+	// without OpenDuration Half-Open would time out right away, it waits for a result instead
+	if openDuration <= 0 || now.Before(halfOpenSince.Add(openDuration)) {
+		return stateValue{State: StateHalfOpen, Since: halfOpenSince}, value.State != StateHalfOpen
+	}
+
+	cycles := now.Sub(halfOpenSince) / (2 * openDuration)
+	halfOpenSince = halfOpenSince.Add(cycles * 2 * openDuration)
+	if now.Before(halfOpenSince.Add(openDuration)) {
+		return stateValue{State: StateHalfOpen, Since: halfOpenSince}, true
+	}
+
+	return stateValue{State: StateOpen, Since: halfOpenSince.Add(openDuration)}, true
+}
+
+// setState persists state under TripKey so every instance sharing the cache sees it
+func (c *circuitBreaker) setState(ctx context.Context, state State, since time.Time) error {
+	if !c.Active {
+		return nil
+	}
+
+	return c.Cache.SetString(ctx, c.TripKey, stateValue{State: state, Since: since}.String(), c.CacheTTL)
+}
+
+// getStateCounterKey with format <trip_key>-<counter>-<since in unix millis>
+// example: cb-trip-loan_disbursement-24h-probe-1683628920000
+func (c *circuitBreaker) getStateCounterKey(counter string, value stateValue) string {
+	return fmt.Sprintf("%s-%s-%d", c.TripKey, counter, value.Since.UnixMilli())
+}
@@ -0,0 +1,198 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "go-circuit-breaker"
+	"go-circuit-breaker/cbtest"
+)
+
+func TestState_String(t *testing.T) {
+	assert.Equal(t, "closed", circuitbreaker.StateClosed.String())
+	assert.Equal(t, "open", circuitbreaker.StateOpen.String())
+	assert.Equal(t, "half_open", circuitbreaker.StateHalfOpen.String())
+}
+
+func TestCircuitBreaker_StateMachine(t *testing.T) {
+	type Response struct {
+		state   circuitbreaker.State
+		allowed []bool
+	}
+
+	testcases := map[string]struct {
+		config   circuitbreaker.StateMachineConfig
+		action   func(ctx context.Context, t *testing.T, cb circuitbreaker.CircuitBreaker, clock *cbtest.Clock)
+		response Response
+	}{
+		"Closed by default": {
+			config: circuitbreaker.DefaultStateMachineConfig,
+			action: func(ctx context.Context, t *testing.T, cb circuitbreaker.CircuitBreaker, clock *cbtest.Clock) {},
+			response: Response{
+				state:   circuitbreaker.StateClosed,
+				allowed: []bool{true, true},
+			},
+		},
+		"Open rejects every call": {
+			config: circuitbreaker.DefaultStateMachineConfig,
+			action: func(ctx context.Context, t *testing.T, cb circuitbreaker.CircuitBreaker, clock *cbtest.Clock) {
+				assert.Nil(t, cb.UpdateTrip(ctx, true))
+			},
+			response: Response{
+				state:   circuitbreaker.StateOpen,
+				allowed: []bool{false, false},
+			},
+		},
+		"Half-Open after open duration allows limited probes": {
+			config: circuitbreaker.StateMachineConfig{
+				OpenDuration:             time.Minute,
+				HalfOpenMaxProbes:        2,
+				HalfOpenSuccessThreshold: 2,
+			},
+			action: func(ctx context.Context, t *testing.T, cb circuitbreaker.CircuitBreaker, clock *cbtest.Clock) {
+				assert.Nil(t, cb.UpdateTrip(ctx, true))
+				clock.Advance(time.Minute)
+			},
+			response: Response{
+				state:   circuitbreaker.StateHalfOpen,
+				allowed: []bool{true, true, false},
+			},
+		},
+		"Half-Open closes after enough successes": {
+			config: circuitbreaker.StateMachineConfig{
+				OpenDuration:             time.Minute,
+				HalfOpenMaxProbes:        2,
+				HalfOpenSuccessThreshold: 2,
+			},
+			action: func(ctx context.Context, t *testing.T, cb circuitbreaker.CircuitBreaker, clock *cbtest.Clock) {
+				assert.Nil(t, cb.UpdateTrip(ctx, true))
+				clock.Advance(time.Minute)
+				assert.Nil(t, cb.RecordResult(ctx, true))
+				assert.Nil(t, cb.RecordResult(ctx, true))
+			},
+			response: Response{
+				state:   circuitbreaker.StateClosed,
+				allowed: []bool{true, true, true},
+			},
+		},
+		"Half-Open opens again when the probe never reports back": {
+			config: circuitbreaker.StateMachineConfig{
+				OpenDuration:             time.Minute,
+				HalfOpenMaxProbes:        1,
+				HalfOpenSuccessThreshold: 1,
+			},
+			action: func(ctx context.Context, t *testing.T, cb circuitbreaker.CircuitBreaker, clock *cbtest.Clock) {
+				assert.Nil(t, cb.UpdateTrip(ctx, true))
+				clock.Advance(time.Minute)
+				allowed, err := cb.Allow(ctx)
+				assert.Nil(t, err)
+				assert.True(t, allowed)
+				clock.Advance(time.Minute)
+			},
+			response: Response{
+				state:   circuitbreaker.StateOpen,
+				allowed: []bool{false},
+			},
+		},
+		"Half-Open after a lost probe has fresh probes": {
+			config: circuitbreaker.StateMachineConfig{
+				OpenDuration:             time.Minute,
+				HalfOpenMaxProbes:        1,
+				HalfOpenSuccessThreshold: 1,
+			},
+			action: func(ctx context.Context, t *testing.T, cb circuitbreaker.CircuitBreaker, clock *cbtest.Clock) {
+				assert.Nil(t, cb.UpdateTrip(ctx, true))
+				clock.Advance(time.Minute)
+				allowed, err := cb.Allow(ctx)
+				assert.Nil(t, err)
+				assert.True(t, allowed)
+				clock.Advance(3 * time.Hour)
+			},
+			response: Response{
+				state:   circuitbreaker.StateHalfOpen,
+				allowed: []bool{true, false},
+			},
+		},
+		"Half-Open opens again on failure": {
+			config: circuitbreaker.StateMachineConfig{
+				OpenDuration:             time.Hour,
+				HalfOpenMaxProbes:        1,
+				HalfOpenSuccessThreshold: 1,
+			},
+			action: func(ctx context.Context, t *testing.T, cb circuitbreaker.CircuitBreaker, clock *cbtest.Clock) {
+				cb.SetStateMachineConfig(circuitbreaker.StateMachineConfig{OpenDuration: 0, HalfOpenMaxProbes: 1, HalfOpenSuccessThreshold: 1})
+				assert.Nil(t, cb.UpdateTrip(ctx, true))
+				state, err := cb.GetState(ctx)
+				assert.Nil(t, err)
+				assert.Equal(t, circuitbreaker.StateHalfOpen, state)
+
+				cb.SetStateMachineConfig(circuitbreaker.StateMachineConfig{OpenDuration: time.Hour, HalfOpenMaxProbes: 1, HalfOpenSuccessThreshold: 1})
+				assert.Nil(t, cb.RecordResult(ctx, false))
+			},
+			response: Response{
+				state:   circuitbreaker.StateOpen,
+				allowed: []bool{false},
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, client := newRedisClient(t)
+			cb, clock := newTestCircuitBreaker(t, client, "test")
+			cb.SetStateMachineConfig(tc.config)
+
+			tc.action(ctx, t, cb, clock)
+
+			state, err := cb.GetState(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.response.state, state)
+
+			for _, expected := range tc.response.allowed {
+				allowed, err := cb.Allow(ctx)
+				assert.Nil(t, err)
+				assert.Equal(t, expected, allowed)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker_StateSharedAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	_, client := newRedisClient(t)
+	clock := cbtest.NewClock(time.Date(2023, time.May, 9, 10, 42, 0, 0, time.UTC))
+	config := circuitbreaker.StateMachineConfig{
+		OpenDuration:             time.Minute,
+		HalfOpenMaxProbes:        1,
+		HalfOpenSuccessThreshold: 1,
+	}
+
+	first, _ := newTestCircuitBreaker(t, client, "test", circuitbreaker.WithClock(clock))
+	first.SetStateMachineConfig(config)
+	second, _ := newTestCircuitBreaker(t, client, "test", circuitbreaker.WithClock(clock))
+	second.SetStateMachineConfig(config)
+
+	assert.Nil(t, first.UpdateTrip(ctx, true))
+	isTripped, err := second.GetTrip(ctx)
+	assert.Nil(t, err)
+	assert.True(t, isTripped)
+
+	clock.Advance(time.Minute)
+
+	// probe quota is shared, only one instance gets to probe
+	firstAllowed, err := first.Allow(ctx)
+	assert.Nil(t, err)
+	secondAllowed, err := second.Allow(ctx)
+	assert.Nil(t, err)
+	assert.True(t, firstAllowed)
+	assert.False(t, secondAllowed)
+
+	assert.Nil(t, first.RecordResult(ctx, true))
+	state, err := second.GetState(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.StateClosed, state)
+}